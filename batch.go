@@ -0,0 +1,396 @@
+package dynamodb_client
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sort"
+	"time"
+)
+
+const maxBatchWriteItems = 25
+
+// BatchItemFailure reports an item of a batch request that could not be
+// written; Index is its position in the slice given to the batch method.
+type BatchItemFailure struct {
+	Index int
+	Key   Key
+	Err   error
+}
+
+type BatchWriteError struct {
+	Failures []BatchItemFailure
+}
+
+func (e *BatchWriteError) Error() string {
+	return fmt.Sprintf("batch write failed for %d item(s), first: %v", len(e.Failures), e.Failures[0].Err)
+}
+
+type batchWriteRequest struct {
+	index   int
+	key     Key
+	request types.WriteRequest
+}
+
+func (r *DynamoDbClient) BatchInsert(items []interface{}) (err error) {
+	return r.BatchInsertCtx(context.TODO(), items)
+}
+
+// BatchInsertCtx puts items with BatchWriteItem requests of up to 25 items,
+// retrying unprocessed items according to the client's RetryPolicy. Items
+// that could not be written are reported through a *BatchWriteError. Since
+// BatchWriteItem refuses a request repeating a key, only the first item of a
+// key is written and the later ones are reported with ErrDuplicateItem.
+// BatchWriteItem takes no condition either, so items whose DynamoDbMetaData
+// has a Version are reported with a *VersionedBatchItemError rather than
+// overwriting concurrent edits; put them with Insert or a Transaction.
+func (r *DynamoDbClient) BatchInsertCtx(ctx context.Context, items []interface{}) (err error) {
+	var requests []batchWriteRequest
+	var failures []BatchItemFailure
+
+	for i, item := range items {
+		var avItem map[string]types.AttributeValue
+		var metaData *DynamoDbMetaData
+
+		avItem, metaData, err = r.marshalItem(item)
+		if err != nil {
+			failures = append(failures, BatchItemFailure{Index: i, Err: err})
+			continue
+		}
+
+		key := keyOfItem(avItem)
+		if nil == key.PK || nil == key.SK {
+			failures = append(failures, BatchItemFailure{Index: i, Key: key, Err: &InvalidKeyError{Key: key, Reason: "PK and SK are required"}})
+			continue
+		}
+
+		if nil != metaData && nil != metaData.Version {
+			failures = append(failures, BatchItemFailure{Index: i, Key: key, Err: &VersionedBatchItemError{Key: key, Version: *metaData.Version}})
+			continue
+		}
+
+		requests = append(requests, batchWriteRequest{
+			index:   i,
+			key:     key,
+			request: types.WriteRequest{PutRequest: &types.PutRequest{Item: avItem}},
+		})
+	}
+
+	return r.batchWrite(ctx, requests, failures)
+}
+
+func (r *DynamoDbClient) BatchDelete(keys []Key) (err error) {
+	return r.BatchDeleteCtx(context.TODO(), keys)
+}
+
+// BatchDeleteCtx deletes keys with BatchWriteItem requests of up to 25 keys,
+// retrying unprocessed keys like BatchInsertCtx. Keys with an IndexName and
+// repeated keys are reported as failures.
+func (r *DynamoDbClient) BatchDeleteCtx(ctx context.Context, keys []Key) (err error) {
+	var requests []batchWriteRequest
+	var failures []BatchItemFailure
+
+	for i, key := range keys {
+		var av map[string]types.AttributeValue
+
+		av, err = marshalPrimaryKey(key)
+		if err != nil {
+			failures = append(failures, BatchItemFailure{Index: i, Key: key, Err: err})
+			continue
+		}
+
+		requests = append(requests, batchWriteRequest{
+			index:   i,
+			key:     key,
+			request: types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: av}},
+		})
+	}
+
+	return r.batchWrite(ctx, requests, failures)
+}
+
+func (r *DynamoDbClient) batchWrite(ctx context.Context, requests []batchWriteRequest, failures []BatchItemFailure) (err error) {
+	requests, failures = dedupeBatchWriteRequests(requests, failures)
+
+	for start := 0; start < len(requests); start += maxBatchWriteItems {
+		end := start + maxBatchWriteItems
+		if end > len(requests) {
+			end = len(requests)
+		}
+
+		failures, err = r.batchWriteChunk(ctx, requests[start:end], failures)
+		if err != nil {
+			return
+		}
+	}
+
+	if len(failures) > 0 {
+		err = &BatchWriteError{Failures: failures}
+	}
+
+	return
+}
+
+func (r *DynamoDbClient) batchWriteChunk(ctx context.Context, pending []batchWriteRequest, failures []BatchItemFailure) (_ []BatchItemFailure, err error) {
+	for attempt := 0; ; attempt++ {
+		var output *dynamodb.BatchWriteItemOutput
+
+		if err = ctx.Err(); err != nil {
+			return failures, err
+		}
+
+		writeRequests := make([]types.WriteRequest, len(pending))
+		for i, p := range pending {
+			writeRequests[i] = p.request
+		}
+
+		output, err = r.dynamoDb.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{r.tableName: writeRequests},
+		})
+		if err != nil {
+			if nil != ctx.Err() {
+				return failures, ctx.Err()
+			}
+
+			for _, p := range pending {
+				failures = append(failures, BatchItemFailure{Index: p.index, Key: p.key, Err: err})
+			}
+
+			return failures, nil
+		}
+
+		unprocessed := map[string]bool{}
+		for _, writeRequest := range output.UnprocessedItems[r.tableName] {
+			unprocessed[writeRequestKey(writeRequest)] = true
+		}
+
+		if 0 == len(unprocessed) {
+			return failures, nil
+		}
+
+		var next []batchWriteRequest
+		for _, p := range pending {
+			if unprocessed[writeRequestKey(p.request)] {
+				next = append(next, p)
+			}
+		}
+
+		pending = next
+
+		if attempt+1 >= r.retryPolicy.MaxAttempts {
+			for _, p := range pending {
+				failures = append(failures, BatchItemFailure{Index: p.index, Key: p.key, Err: ErrUnprocessedItem})
+			}
+
+			return failures, nil
+		}
+
+		if err = sleepCtx(ctx, r.retryPolicy.backoff(attempt)); err != nil {
+			return failures, err
+		}
+	}
+}
+
+// dedupeBatchWriteRequests keeps the first request of every key and reports
+// the later ones with ErrDuplicateItem.
+func dedupeBatchWriteRequests(requests []batchWriteRequest, failures []BatchItemFailure) ([]batchWriteRequest, []BatchItemFailure) {
+	var unique []batchWriteRequest
+
+	seen := make(map[string]bool, len(requests))
+	for _, p := range requests {
+		k := writeRequestKey(p.request)
+		if seen[k] {
+			failures = append(failures, BatchItemFailure{Index: p.index, Key: p.key, Err: ErrDuplicateItem})
+			continue
+		}
+
+		seen[k] = true
+		unique = append(unique, p)
+	}
+
+	return unique, failures
+}
+
+func writeRequestKey(writeRequest types.WriteRequest) string {
+	if nil != writeRequest.PutRequest {
+		return itemKeyString(writeRequest.PutRequest.Item)
+	}
+
+	return itemKeyString(writeRequest.DeleteRequest.Key)
+}
+
+// marshalPrimaryKey builds the table key of key, ignoring its sort key type.
+// A key of an index is refused, since batch and transactional operations only
+// address items through the table key.
+func marshalPrimaryKey(key Key) (av map[string]types.AttributeValue, err error) {
+	if nil != key.IndexName {
+		err = &InvalidKeyError{Key: key, Reason: "indexes are not supported, use the table key"}
+		return
+	}
+
+	if nil == key.PK || nil == key.SK {
+		err = &InvalidKeyError{Key: key, Reason: "PK and SK are required"}
+		return
+	}
+
+	return attributevalue.MarshalMap(Key{PK: key.PK, SK: key.SK})
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+const maxBatchGetItems = 100
+
+type BatchGetError struct {
+	Failures []BatchItemFailure
+}
+
+func (e *BatchGetError) Error() string {
+	return fmt.Sprintf("batch get failed for %d key(s), first: %v", len(e.Failures), e.Failures[0].Err)
+}
+
+func (r *DynamoDbClient) BatchGet(keys []Key, arrayOfField string, options ...ReadOption) (items []map[string]types.AttributeValue, err error) {
+	return r.BatchGetCtx(context.TODO(), keys, arrayOfField, options...)
+}
+
+// BatchGetCtx fetches keys with BatchGetItem requests of up to 100 keys,
+// retrying unprocessed keys according to the client's RetryPolicy. items[i]
+// holds the item of keys[i], or nil when it does not exist. PK and SK are
+// always projected so that items can be matched to their keys. Keys that could
+// not be read, such as keys with an IndexName, are reported through a
+// *BatchGetError next to the items that were.
+func (r *DynamoDbClient) BatchGetCtx(ctx context.Context, keys []Key, arrayOfField string, options ...ReadOption) (items []map[string]types.AttributeValue, err error) {
+	var failures []BatchItemFailure
+	var unique []map[string]types.AttributeValue
+	var projectionExpression *string
+	var expressionAttributeNames map[string]string
+
+	indexes := map[string][]int{}
+	items = make([]map[string]types.AttributeValue, len(keys))
+
+	for i, key := range keys {
+		var av map[string]types.AttributeValue
+
+		av, err = marshalPrimaryKey(key)
+		if err != nil {
+			failures = append(failures, BatchItemFailure{Index: i, Key: key, Err: err})
+			continue
+		}
+
+		k := itemKeyString(av)
+		if _, ok := indexes[k]; !ok {
+			unique = append(unique, av)
+		}
+
+		indexes[k] = append(indexes[k], i)
+	}
+
+	o := newReadOption(options)
+
+	projectionExpression, expressionAttributeNames, err = o.buildProjection(arrayOfField, "PK", "SK")
+	if err != nil {
+		return
+	}
+
+	for start := 0; start < len(unique); start += maxBatchGetItems {
+		end := start + maxBatchGetItems
+		if end > len(unique) {
+			end = len(unique)
+		}
+
+		pending := unique[start:end]
+
+		for attempt := 0; len(pending) > 0; attempt++ {
+			var output *dynamodb.BatchGetItemOutput
+
+			if err = ctx.Err(); err != nil {
+				return
+			}
+
+			output, err = r.dynamoDb.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: map[string]types.KeysAndAttributes{
+					r.tableName: {
+						Keys:                     pending,
+						ProjectionExpression:     projectionExpression,
+						ExpressionAttributeNames: expressionAttributeNames,
+						ConsistentRead:           o.consistentReadInput(),
+					},
+				},
+			})
+			if err != nil {
+				if nil != ctx.Err() {
+					err = ctx.Err()
+					return
+				}
+
+				for _, av := range pending {
+					for _, i := range indexes[itemKeyString(av)] {
+						failures = append(failures, BatchItemFailure{Index: i, Key: keys[i], Err: err})
+					}
+				}
+
+				break
+			}
+
+			for _, item := range output.Responses[r.tableName] {
+				for _, i := range indexes[itemKeyString(item)] {
+					items[i] = item
+				}
+			}
+
+			pending = output.UnprocessedKeys[r.tableName].Keys
+
+			if len(pending) > 0 && attempt+1 >= r.retryPolicy.MaxAttempts {
+				for _, av := range pending {
+					for _, i := range indexes[itemKeyString(av)] {
+						failures = append(failures, BatchItemFailure{Index: i, Key: keys[i], Err: ErrUnprocessedItem})
+					}
+				}
+
+				break
+			}
+
+			if len(pending) > 0 {
+				if err = sleepCtx(ctx, r.retryPolicy.backoff(attempt)); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	err = nil
+	if len(failures) > 0 {
+		sort.Slice(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+		err = &BatchGetError{Failures: failures}
+	}
+
+	return
+}
+
+func itemKeyString(item map[string]types.AttributeValue) string {
+	key := keyOfItem(item)
+
+	return aws.ToString(key.PK) + "\x00" + aws.ToString(key.SK)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
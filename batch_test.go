@@ -0,0 +1,256 @@
+package dynamodb_client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	dc "gitlab.com/ptami_lib/dynamodb-client"
+	"gitlab.com/ptami_lib/dynamodb-client/dynamodbtest"
+	"testing"
+)
+
+// throttledTable leaves the first request of every batch call unprocessed
+// during the first throttled calls, like a throttled DynamoDB table would.
+type throttledTable struct {
+	*dynamodbtest.Table
+	throttled int
+	calls     int
+}
+
+func (r *throttledTable) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	var unprocessed = map[string][]types.WriteRequest{}
+
+	r.calls++
+
+	if r.calls <= r.throttled {
+		requestItems := map[string][]types.WriteRequest{}
+
+		for tableName, requests := range params.RequestItems {
+			unprocessed[tableName] = requests[:1]
+
+			if len(requests) > 1 {
+				requestItems[tableName] = requests[1:]
+			}
+		}
+
+		if 0 == len(requestItems) {
+			return &dynamodb.BatchWriteItemOutput{UnprocessedItems: unprocessed}, nil
+		}
+
+		params = &dynamodb.BatchWriteItemInput{RequestItems: requestItems}
+	}
+
+	output, err := r.Table.BatchWriteItem(ctx, params, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	output.UnprocessedItems = unprocessed
+
+	return output, nil
+}
+
+func (r *throttledTable) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	var unprocessed = map[string]types.KeysAndAttributes{}
+
+	r.calls++
+
+	if r.calls <= r.throttled {
+		requestItems := map[string]types.KeysAndAttributes{}
+
+		for tableName, request := range params.RequestItems {
+			held, rest := request, request
+			held.Keys, rest.Keys = request.Keys[:1], request.Keys[1:]
+			unprocessed[tableName] = held
+
+			if len(rest.Keys) > 0 {
+				requestItems[tableName] = rest
+			}
+		}
+
+		if 0 == len(requestItems) {
+			return &dynamodb.BatchGetItemOutput{UnprocessedKeys: unprocessed}, nil
+		}
+
+		params = &dynamodb.BatchGetItemInput{RequestItems: requestItems}
+	}
+
+	output, err := r.Table.BatchGetItem(ctx, params, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	output.UnprocessedKeys = unprocessed
+
+	return output, nil
+}
+
+func newThrottledClient(throttled int) (*dc.DynamoDbClient, *throttledTable) {
+	table := &throttledTable{Table: dynamodbtest.New("t"), throttled: throttled}
+
+	return dc.New(table, "t", dc.WithRetryPolicy(dc.RetryPolicy{MaxAttempts: 3})), table
+}
+
+func testRecords(count int) (items []interface{}) {
+	for i := 0; i < count; i++ {
+		record := testRecord{Count: i}
+		record.PK, record.SK = "P", fmt.Sprintf("S#%02d", i)
+		items = append(items, record)
+	}
+
+	return
+}
+
+func TestBatchInsertRetries(t *testing.T) {
+	client, table := newThrottledClient(2)
+
+	if err := client.BatchInsert(testRecords(30)); err != nil {
+		t.Fatalf("BatchInsert() error = %v", err)
+	}
+
+	// the first chunk is retried twice before the second chunk is sent
+	if 30 != len(table.Items()) || 4 != table.calls {
+		t.Errorf("BatchInsert() wrote %d item(s) in %d calls, want 30 in 4", len(table.Items()), table.calls)
+	}
+}
+
+func TestBatchInsertUnprocessed(t *testing.T) {
+	client, table := newThrottledClient(10)
+
+	err := client.BatchInsert(testRecords(3))
+
+	var batchWriteErr *dc.BatchWriteError
+	if !errors.As(err, &batchWriteErr) || 1 != len(batchWriteErr.Failures) || 0 != batchWriteErr.Failures[0].Index || !errors.Is(batchWriteErr.Failures[0].Err, dc.ErrUnprocessedItem) {
+		t.Fatalf("BatchInsert() error = %v, want ErrUnprocessedItem for item 0", err)
+	}
+
+	if 2 != len(table.Items()) || 3 != table.calls {
+		t.Errorf("BatchInsert() wrote %d item(s) in %d calls, want 2 in 3", len(table.Items()), table.calls)
+	}
+}
+
+func TestBatchGetRetries(t *testing.T) {
+	client, table := newThrottledClient(0)
+	if err := client.BatchInsert(testRecords(3)); err != nil {
+		t.Fatalf("BatchInsert() error = %v", err)
+	}
+
+	keys := []dc.Key{testKey("S#00"), testKey("S#01"), testKey("S#02")}
+
+	table.calls, table.throttled = 0, 2
+
+	items, err := client.BatchGet(keys, "")
+	if err != nil || 3 != len(items) || nil == items[0] || nil == items[1] || nil == items[2] || 3 != table.calls {
+		t.Errorf("BatchGet() = %d item(s), %v in %d calls, want 3 in 3", len(items), err, table.calls)
+	}
+
+	table.calls, table.throttled = 0, 10
+
+	items, err = client.BatchGet(keys, "")
+
+	var batchGetErr *dc.BatchGetError
+	if !errors.As(err, &batchGetErr) || 1 != len(batchGetErr.Failures) || 0 != batchGetErr.Failures[0].Index || !errors.Is(batchGetErr.Failures[0].Err, dc.ErrUnprocessedItem) {
+		t.Fatalf("BatchGet() error = %v, want ErrUnprocessedItem for key 0", err)
+	}
+
+	if nil != items[0] || nil == items[1] || nil == items[2] || 3 != table.calls {
+		t.Errorf("BatchGet() = %v in %d calls, want keys 1 and 2 in 3", items, table.calls)
+	}
+}
+
+func TestIndexKeysRefused(t *testing.T) {
+	client, table := newTestClient(t)
+	insertTestRecords(t, client, 2)
+
+	indexKey := dc.Key{PK: aws.String("P"), SK: aws.String("S#01"), IndexName: aws.String("GSI1")}
+
+	items, err := client.BatchGet([]dc.Key{indexKey, testKey("S#00")}, "")
+	var batchGetErr *dc.BatchGetError
+	if !errors.As(err, &batchGetErr) || 1 != len(batchGetErr.Failures) || 0 != batchGetErr.Failures[0].Index || !errors.Is(batchGetErr.Failures[0].Err, dc.ErrInvalidKey) {
+		t.Errorf("BatchGet() error = %v, want a failure for the index key", err)
+	}
+
+	if 2 != len(items) || nil != items[0] || nil == items[1] {
+		t.Errorf("BatchGet() = %v, want only the table key item", items)
+	}
+
+	err = client.BatchDelete([]dc.Key{indexKey})
+	var batchWriteErr *dc.BatchWriteError
+	if !errors.As(err, &batchWriteErr) || 1 != len(batchWriteErr.Failures) || !errors.Is(batchWriteErr.Failures[0].Err, dc.ErrInvalidKey) {
+		t.Errorf("BatchDelete() error = %v, want a failure for the index key", err)
+	}
+
+	err = client.NewTransaction().Delete(indexKey).Commit()
+	if !errors.Is(err, dc.ErrInvalidKey) {
+		t.Errorf("Transaction.Delete() error = %v, want ErrInvalidKey", err)
+	}
+
+	_, err = client.TransactGet([]dc.Key{indexKey})
+	if !errors.Is(err, dc.ErrInvalidKey) {
+		t.Errorf("TransactGet() error = %v, want ErrInvalidKey", err)
+	}
+
+	if 2 != len(table.Items()) {
+		t.Errorf("an index key deleted an item: %d item(s) left", len(table.Items()))
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	record := testRecord{Status: "A"}
+	record.PK = "P"
+
+	err := client.BatchInsert([]interface{}{record})
+	var batchWriteErr *dc.BatchWriteError
+	if !errors.As(err, &batchWriteErr) || 1 != len(batchWriteErr.Failures) || !errors.Is(batchWriteErr.Failures[0].Err, dc.ErrInvalidKey) {
+		t.Errorf("BatchInsert() without SK error = %v, want ErrInvalidKey", err)
+	}
+
+	err = client.BatchDelete([]dc.Key{{PK: aws.String("P")}})
+	if !errors.As(err, &batchWriteErr) || 1 != len(batchWriteErr.Failures) || !errors.Is(batchWriteErr.Failures[0].Err, dc.ErrInvalidKey) {
+		t.Errorf("BatchDelete() without SK error = %v, want ErrInvalidKey", err)
+	}
+
+	if err = client.NewTransaction().Commit(); !errors.Is(err, dc.ErrInvalidTransactionSize) {
+		t.Errorf("empty Transaction.Commit() error = %v, want ErrInvalidTransactionSize", err)
+	}
+
+	if _, err = client.TransactGet(nil); !errors.Is(err, dc.ErrInvalidTransactionSize) {
+		t.Errorf("TransactGet() without keys error = %v, want ErrInvalidTransactionSize", err)
+	}
+}
+
+func TestBatchWriteDuplicates(t *testing.T) {
+	client, table := newTestClient(t)
+
+	first := testRecord{Status: "first"}
+	first.PK, first.SK = "P", "S#00"
+	second := testRecord{Status: "second"}
+	second.PK, second.SK = "P", "S#00"
+	other := testRecord{Status: "other"}
+	other.PK, other.SK = "P", "S#01"
+
+	err := client.BatchInsert([]interface{}{first, other, second})
+	var batchWriteErr *dc.BatchWriteError
+	if !errors.As(err, &batchWriteErr) || 1 != len(batchWriteErr.Failures) || 2 != batchWriteErr.Failures[0].Index || !errors.Is(batchWriteErr.Failures[0].Err, dc.ErrDuplicateItem) {
+		t.Fatalf("BatchInsert() error = %v, want ErrDuplicateItem for item 2", err)
+	}
+
+	got, err := dc.GetItemAs[testRecord](client, testKey("S#00"))
+	if err != nil || "first" != got.Status || 2 != len(table.Items()) {
+		t.Errorf("BatchInsert() kept %q (%v) and %d item(s), want first and 2", got.Status, err, len(table.Items()))
+	}
+
+	err = client.BatchDelete([]dc.Key{testKey("S#00"), testKey("S#00")})
+	if !errors.As(err, &batchWriteErr) || 1 != len(batchWriteErr.Failures) || 1 != batchWriteErr.Failures[0].Index {
+		t.Errorf("BatchDelete() error = %v, want ErrDuplicateItem for key 1", err)
+	}
+
+	if 1 != len(table.Items()) {
+		t.Errorf("BatchDelete() left %d item(s), want 1", len(table.Items()))
+	}
+}
@@ -0,0 +1,206 @@
+package dynamodb_client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"strconv"
+	"strings"
+)
+
+// WriteOption adds a condition to Insert, UpdateItem, DeleteItem and to the
+// operations of a Transaction; a write whose condition fails returns a
+// *ConditionFailedError, or a *VersionConflictError when WithVersion is its
+// only condition.
+type WriteOption func(*writeOption)
+
+type writeOption struct {
+	ifNotExists bool
+	ifExists    bool
+	filters     []map[string]interface{}
+	conditions  []Condition
+	version     *uint64
+}
+
+// IfNotExists only writes when no item exists at the key, e.g. to insert
+// without overwriting.
+func IfNotExists() WriteOption {
+	return func(o *writeOption) {
+		o.ifNotExists = true
+	}
+}
+
+// IfExists only writes when an item exists at the key, e.g. to update without
+// creating the item.
+func IfExists() WriteOption {
+	return func(o *writeOption) {
+		o.ifExists = true
+	}
+}
+
+// WithConditionFilter only writes when the stored item matches filter, given
+// in the same shape as QueryOption.Filter.
+func WithConditionFilter(filter map[string]interface{}) WriteOption {
+	return func(o *writeOption) {
+		o.filters = append(o.filters, filter)
+	}
+}
+
+// WithCondition only writes when the stored item matches condition.
+func WithCondition(condition Condition) WriteOption {
+	return func(o *writeOption) {
+		o.conditions = append(o.conditions, condition)
+	}
+}
+
+// WithVersion enables optimistic locking: the write only succeeds when the
+// stored Version equals version, and stores version + 1. Version 0 stands for
+// an item that was never versioned, so Insert then requires the item not to
+// exist. A stale version fails with a *VersionConflictError; combined with
+// other conditions, a failed write returns a *ConditionFailedError since
+// DynamoDB does not tell which condition failed. Insert and Transaction.Put
+// default to the Version of the DynamoDbMetaData of the item when it is set,
+// and store version + 1 back into it once written.
+func WithVersion(version uint64) WriteOption {
+	return func(o *writeOption) {
+		o.version = &version
+	}
+}
+
+func newWriteOption(options []WriteOption) (o writeOption) {
+	for _, option := range options {
+		option(&o)
+	}
+
+	return
+}
+
+// versionFromMetaData takes the expected version from the Version of an item
+// when WithVersion was not given.
+func (o *writeOption) versionFromMetaData(metaData *DynamoDbMetaData) {
+	if nil == o.version && nil != metaData && nil != metaData.Version {
+		version := *metaData.Version
+		o.version = &version
+	}
+}
+
+// versionOnly reports whether the version check is the only condition of the
+// write, so that its failure can only be a version conflict.
+func (o writeOption) versionOnly() bool {
+	return nil != o.version && !o.ifNotExists && !o.ifExists && 0 == len(o.filters) && 0 == len(o.conditions)
+}
+
+// storeVersion sets the Version written by a versioned put on its item.
+func (o writeOption) storeVersion(metaData *DynamoDbMetaData) {
+	if nil != o.version && nil != metaData {
+		version := *o.version + 1
+		metaData.Version = &version
+	}
+}
+
+// applyVersion stores the next Version in a marshalled item.
+func (o writeOption) applyVersion(avItem map[string]types.AttributeValue) {
+	if nil != o.version {
+		avItem["Version"] = &types.AttributeValueMemberN{Value: strconv.FormatUint(*o.version+1, 10)}
+	}
+}
+
+// applyVersionToPropertyMap sets the next Version in an UpdateItem propertyMap.
+func (o writeOption) applyVersionToPropertyMap(propertyMap map[string]interface{}) {
+	if nil != o.version {
+		propertyMap["Version"] = *o.version + 1
+	}
+}
+
+// buildCondition combines the options into a condition expression. Their
+// names and values are added to expressionAttributeNames and
+// expressionAttributeValues, which are returned as nil when left empty.
+// notExistsOnInitialVersion selects how version 0 is checked: by the absence
+// of the whole item for puts, or of its Version for updates.
+func (o writeOption) buildCondition(notExistsOnInitialVersion bool, expressionAttributeNames map[string]string, expressionAttributeValues map[string]types.AttributeValue) (conditionExpression *string, _ map[string]string, _ map[string]types.AttributeValue, err error) {
+	var conditions []string
+
+	if nil == expressionAttributeNames {
+		expressionAttributeNames = map[string]string{}
+	}
+
+	if nil == expressionAttributeValues {
+		expressionAttributeValues = map[string]types.AttributeValue{}
+	}
+
+	if o.ifNotExists {
+		conditions = append(conditions, "attribute_not_exists(PK)")
+	}
+
+	if o.ifExists {
+		conditions = append(conditions, "attribute_exists(PK)")
+	}
+
+	for _, filter := range o.filters {
+		var filterExpression string
+
+		filterExpression, err = processQueryOptionFilter(filter, expressionAttributeValues, expressionAttributeNames)
+		if err != nil {
+			return
+		}
+
+		if "" != filterExpression {
+			conditions = append(conditions, filterExpression)
+		}
+	}
+
+	for _, condition := range o.conditions {
+		var expression string
+
+		expression, err = buildConditionExpression(condition, expressionAttributeValues, expressionAttributeNames)
+		if err != nil {
+			return
+		}
+
+		if "" != expression {
+			conditions = append(conditions, fmt.Sprintf("(%s)", expression))
+		}
+	}
+
+	if nil != o.version {
+		a := newPlaceholderAllocator(expressionAttributeNames, expressionAttributeValues)
+
+		switch {
+		case 0 != *o.version:
+			version := a.value(&types.AttributeValueMemberN{Value: strconv.FormatUint(*o.version, 10)})
+			conditions = append(conditions, fmt.Sprintf("%s = %s", a.name("Version"), version))
+		case notExistsOnInitialVersion:
+			conditions = append(conditions, "attribute_not_exists(PK)")
+		default:
+			conditions = append(conditions, fmt.Sprintf("attribute_not_exists(%s)", a.name("Version")))
+		}
+	}
+
+	if len(conditions) > 0 {
+		conditionExpression = aws.String(strings.Join(conditions, " AND "))
+	}
+
+	if 0 == len(expressionAttributeNames) {
+		expressionAttributeNames = nil
+	}
+
+	if 0 == len(expressionAttributeValues) {
+		expressionAttributeValues = nil
+	}
+
+	return conditionExpression, expressionAttributeNames, expressionAttributeValues, nil
+}
+
+// wrapError maps a failed condition of a write on key to a
+// *VersionConflictError when the version check is its only condition and to a
+// *ConditionFailedError otherwise.
+func (o writeOption) wrapError(key Key, err error) error {
+	var conditionalCheckFailed *types.ConditionalCheckFailedException
+
+	if o.versionOnly() && errors.As(err, &conditionalCheckFailed) {
+		return &VersionConflictError{Key: key, Version: *o.version, Err: err}
+	}
+
+	return wrapConditionFailed(key, err)
+}
@@ -0,0 +1,111 @@
+package dynamodb_client_test
+
+import (
+	"errors"
+	dc "gitlab.com/ptami_lib/dynamodb-client"
+	"testing"
+)
+
+func TestVersionOfMetaData(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	record := &testRecord{}
+	record.PK, record.SK = "P", "S#00"
+
+	if err := client.Insert(record, dc.WithVersion(0)); err != nil {
+		t.Fatalf("Insert(WithVersion(0)) error = %v", err)
+	}
+
+	if nil == record.Version || 1 != *record.Version {
+		t.Fatalf("Version = %v, want 1", record.Version)
+	}
+
+	if err := client.Insert(record); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	if 2 != *record.Version {
+		t.Errorf("Version = %d, want 2", *record.Version)
+	}
+
+	stale := *record
+	version := uint64(1)
+	stale.Version = &version
+
+	err := client.Insert(&stale)
+	if !errors.Is(err, dc.ErrVersionConflict) {
+		t.Errorf("Insert() of a stale version error = %v, want ErrVersionConflict", err)
+	}
+
+	if 1 != *stale.Version {
+		t.Errorf("a failed Insert() changed Version to %d", *stale.Version)
+	}
+
+	if err = client.NewTransaction().Put(record).Commit(); err != nil {
+		t.Fatalf("Transaction.Put() error = %v", err)
+	}
+
+	if 3 != *record.Version {
+		t.Errorf("Version = %d, want 3", *record.Version)
+	}
+
+	got, err := dc.GetItemAs[testRecord](client, testKey("S#00"))
+	if err != nil || nil == got.Version || 3 != *got.Version {
+		t.Errorf("stored Version = %v, %v, want 3", got.Version, err)
+	}
+
+	unversioned := &testRecord{}
+	unversioned.PK, unversioned.SK = "P", "S#01"
+
+	if err = client.Insert(unversioned); err != nil || nil != unversioned.Version {
+		t.Errorf("Insert() of an unversioned item = %v, %v, want no Version", unversioned.Version, err)
+	}
+}
+
+func TestVersionConflict(t *testing.T) {
+	client, table := newTestClient(t)
+
+	record := &testRecord{Status: "A"}
+	record.PK, record.SK = "P", "S#00"
+
+	if err := client.Insert(record, dc.WithVersion(0)); err != nil {
+		t.Fatalf("Insert(WithVersion(0)) error = %v", err)
+	}
+
+	_, err := client.UpdateItem(testKey("S#00"), map[string]interface{}{"Count": 1}, dc.WithVersion(1), dc.WithCondition(dc.Eq("Status", "B")))
+	if !errors.Is(err, dc.ErrConditionFailed) || errors.Is(err, dc.ErrVersionConflict) {
+		t.Errorf("UpdateItem() failing another condition error = %v, want ErrConditionFailed only", err)
+	}
+
+	_, err = client.UpdateItem(testKey("S#00"), map[string]interface{}{"Count": 1}, dc.WithVersion(2))
+	if !errors.Is(err, dc.ErrVersionConflict) {
+		t.Errorf("UpdateItem() of a stale version error = %v, want ErrVersionConflict", err)
+	}
+
+	stale := *record
+	version := uint64(0)
+	stale.Version = &version
+
+	err = client.NewTransaction().Put(&stale).Commit()
+	if !errors.Is(err, dc.ErrVersionConflict) || !errors.Is(err, dc.ErrConditionFailed) {
+		t.Errorf("Transaction.Put() of a stale version error = %v, want ErrVersionConflict", err)
+	}
+
+	err = client.NewTransaction().Put(record).Delete(testKey("S#01"), dc.IfExists()).Commit()
+	if !errors.Is(err, dc.ErrConditionFailed) || errors.Is(err, dc.ErrVersionConflict) {
+		t.Errorf("Transaction.Delete() failing IfExists error = %v, want ErrConditionFailed only", err)
+	}
+
+	record.Status = "B"
+
+	err = client.BatchInsert([]interface{}{record})
+	var batchWriteErr *dc.BatchWriteError
+	if !errors.As(err, &batchWriteErr) || 1 != len(batchWriteErr.Failures) || !errors.Is(batchWriteErr.Failures[0].Err, dc.ErrVersionedBatchItem) {
+		t.Errorf("BatchInsert() of a versioned item error = %v, want ErrVersionedBatchItem", err)
+	}
+
+	got, err := dc.GetItemAs[testRecord](client, testKey("S#00"))
+	if err != nil || "A" != got.Status || 1 != len(table.Items()) {
+		t.Errorf("stored item = %+v, %v, want the first Insert() only", got, err)
+	}
+}
@@ -0,0 +1,196 @@
+package dynamodb_client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"reflect"
+	"strings"
+)
+
+// Cursor is an opaque pagination token wrapping the LastEvaluatedKey of a
+// query. It is bound to the index and key condition that produced it and,
+// when the client has a signing key, carries an HMAC of its content.
+type Cursor string
+
+type cursorPayload struct {
+	Binding string                 `json:"b"`
+	Key     map[string]cursorValue `json:"k"`
+}
+
+type cursorValue struct {
+	S *string `json:"s,omitempty"`
+	N *string `json:"n,omitempty"`
+	B []byte  `json:"b,omitempty"`
+}
+
+// WithCursorSigningKey makes the client sign the cursors it returns and
+// reject any cursor whose signature does not match.
+func WithCursorSigningKey(signingKey []byte) Option {
+	return func(r *DynamoDbClient) {
+		r.cursorSigningKey = signingKey
+	}
+}
+
+// cursorBinding identifies the query a cursor belongs to.
+func cursorBinding(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (r *DynamoDbClient) encodeCursor(binding string, lastEvaluatedKey map[string]types.AttributeValue) (cursor Cursor, err error) {
+	if 0 == len(lastEvaluatedKey) {
+		return
+	}
+
+	payload := cursorPayload{
+		Binding: binding,
+		Key:     make(map[string]cursorValue, len(lastEvaluatedKey)),
+	}
+
+	for name, av := range lastEvaluatedKey {
+		switch v := av.(type) {
+		case *types.AttributeValueMemberS:
+			payload.Key[name] = cursorValue{S: &v.Value}
+		case *types.AttributeValueMemberN:
+			payload.Key[name] = cursorValue{N: &v.Value}
+		case *types.AttributeValueMemberB:
+			payload.Key[name] = cursorValue{B: v.Value}
+		default:
+			err = fmt.Errorf("encode cursor: unsupported key attribute type (%s: %T)", name, av)
+			return
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	if nil != r.cursorSigningKey {
+		encoded = fmt.Sprintf("%s.%s", encoded, base64.RawURLEncoding.EncodeToString(r.signCursor(data)))
+	}
+
+	cursor = Cursor(encoded)
+
+	return
+}
+
+func (r *DynamoDbClient) decodeCursor(binding string, cursor Cursor) (exclusiveStartKey map[string]types.AttributeValue, err error) {
+	if "" == cursor {
+		return
+	}
+
+	encoded, signature, signed := strings.Cut(string(cursor), ".")
+	if signed != (nil != r.cursorSigningKey) {
+		err = &InvalidCursorError{Reason: "unexpected signature"}
+		if nil != r.cursorSigningKey {
+			err = &InvalidCursorError{Reason: "missing signature"}
+		}
+		return
+	}
+
+	data, decodeErr := base64.RawURLEncoding.DecodeString(encoded)
+	if decodeErr != nil {
+		err = &InvalidCursorError{Reason: "malformed encoding"}
+		return
+	}
+
+	if signed {
+		mac, decodeErr := base64.RawURLEncoding.DecodeString(signature)
+		if decodeErr != nil || !hmac.Equal(mac, r.signCursor(data)) {
+			err = &InvalidCursorError{Reason: "signature mismatch"}
+			return
+		}
+	}
+
+	var payload cursorPayload
+	if json.Unmarshal(data, &payload) != nil || 0 == len(payload.Key) {
+		err = &InvalidCursorError{Reason: "malformed content"}
+		return
+	}
+
+	if binding != payload.Binding {
+		err = &InvalidCursorError{Reason: "issued for another query"}
+		return
+	}
+
+	exclusiveStartKey = make(map[string]types.AttributeValue, len(payload.Key))
+	for name, v := range payload.Key {
+		switch {
+		case nil != v.S:
+			exclusiveStartKey[name] = &types.AttributeValueMemberS{Value: *v.S}
+		case nil != v.N:
+			exclusiveStartKey[name] = &types.AttributeValueMemberN{Value: *v.N}
+		case nil != v.B:
+			exclusiveStartKey[name] = &types.AttributeValueMemberB{Value: v.B}
+		default:
+			err = &InvalidCursorError{Reason: "malformed content"}
+			return
+		}
+	}
+
+	return
+}
+
+func (r *DynamoDbClient) signCursor(data []byte) []byte {
+	mac := hmac.New(sha256.New, r.cursorSigningKey)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// decodeLastEvaluatedKey reads QueryOptionPage.LastEvaluatedKey, which is
+// either a Cursor (or its string form) or, for older callers, the key map
+// itself. A key map carries neither binding nor signature, so it is refused
+// when the client signs its cursors, and must otherwise hold the same values
+// as partition, the partition key of the query.
+func (r *DynamoDbClient) decodeLastEvaluatedKey(binding string, partition map[string]types.AttributeValue, lastEvaluatedKey interface{}) (exclusiveStartKey map[string]types.AttributeValue, err error) {
+	switch v := lastEvaluatedKey.(type) {
+	case nil:
+		return
+	case Cursor:
+		return r.decodeCursor(binding, v)
+	case string:
+		return r.decodeCursor(binding, Cursor(v))
+	}
+
+	value := reflect.ValueOf(lastEvaluatedKey)
+	for reflect.Ptr == value.Kind() && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if reflect.Map != value.Kind() {
+		err = &InvalidCursorError{Reason: fmt.Sprintf("unsupported type %T", lastEvaluatedKey)}
+		return
+	}
+
+	if 0 == value.Len() {
+		return
+	}
+
+	if nil != r.cursorSigningKey {
+		err = &InvalidCursorError{Reason: "unsigned key map"}
+		return
+	}
+
+	exclusiveStartKey, err = attributevalue.MarshalMap(value.Interface())
+	if err != nil {
+		return
+	}
+
+	for name, av := range partition {
+		if !reflect.DeepEqual(av, exclusiveStartKey[name]) {
+			exclusiveStartKey = nil
+			err = &InvalidCursorError{Reason: "issued for another query"}
+			return
+		}
+	}
+
+	return
+}
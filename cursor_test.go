@@ -0,0 +1,143 @@
+package dynamodb_client_test
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dc "gitlab.com/ptami_lib/dynamodb-client"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readAllPages reads the records of listKey page by page, following the cursors.
+func readAllPages(t *testing.T, client *dc.DynamoDbClient, queryOption dc.QueryOption) (got []int, pages int) {
+	t.Helper()
+
+	var cursor dc.Cursor
+
+	for {
+		queryOption.Page = &dc.QueryOptionPage{PageSize: 2, LastEvaluatedKey: cursor}
+
+		records, next, err := dc.ListItemsAs[testRecord](client, listKey(), "", queryOption)
+		if err != nil {
+			t.Fatalf("ListItemsAs() error = %v", err)
+		}
+
+		got = append(got, counts(records)...)
+		pages++
+
+		if "" == next {
+			return
+		}
+
+		if pages > 10 {
+			t.Fatalf("ListItemsAs() does not end")
+		}
+
+		cursor = next
+	}
+}
+
+func TestCursorPages(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     []dc.Option
+		queryOption dc.QueryOption
+		want        []int
+	}{
+		{"unsigned", nil, dc.QueryOption{}, []int{4, 3, 2, 1, 0}},
+		{"signed", []dc.Option{dc.WithCursorSigningKey([]byte("secret"))}, dc.QueryOption{}, []int{4, 3, 2, 1, 0}},
+		{"forward", nil, dc.QueryOption{ScanIndexForward: aws.Bool(true)}, []int{0, 1, 2, 3, 4}},
+		{"filtered", nil, dc.QueryOption{Filter: map[string]interface{}{
+			"status": map[string]interface{}{"field": "Status", "type": "const", "keyword": "A"},
+		}}, []int{3, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t, tt.options...)
+			insertTestRecords(t, client, 5)
+
+			got, pages := readAllPages(t, client, tt.queryOption)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pages = %v, want %v", got, tt.want)
+			}
+
+			if 3 != pages {
+				t.Errorf("read %d pages, want 3", pages)
+			}
+		})
+	}
+}
+
+func TestCursorRejected(t *testing.T) {
+	signed, _ := newTestClient(t, dc.WithCursorSigningKey([]byte("secret")))
+	insertTestRecords(t, signed, 5)
+
+	_, cursor, err := signed.GetItemList(listKey(), "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: 2}})
+	if err != nil || "" == cursor {
+		t.Fatalf("GetItemList() = %q, %v", cursor, err)
+	}
+
+	encoded, signature, _ := strings.Cut(string(cursor), ".")
+	otherKey, _ := newTestClient(t, dc.WithCursorSigningKey([]byte("other")))
+	unsigned, _ := newTestClient(t)
+
+	tests := []struct {
+		name   string
+		client *dc.DynamoDbClient
+		key    dc.Key
+		cursor interface{}
+		want   string
+	}{
+		{"tampered content", signed, listKey(), dc.Cursor("e30." + signature), "signature mismatch"},
+		{"missing signature", signed, listKey(), dc.Cursor(encoded), "missing signature"},
+		{"other signing key", otherKey, listKey(), cursor, "signature mismatch"},
+		{"unexpected signature", unsigned, listKey(), cursor, "unexpected signature"},
+		{"another query", signed, dc.Key{PK: aws.String("Q"), IndexName: aws.String("GSI1")}, cursor, "issued for another query"},
+		{"malformed", unsigned, listKey(), "!!", "malformed encoding"},
+		{"unsupported type", unsigned, listKey(), 42, "unsupported type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.client.GetItemList(tt.key, "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: 2, LastEvaluatedKey: tt.cursor}})
+			if !errors.Is(err, dc.ErrInvalidCursor) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("GetItemList() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorRawKeyMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []dc.Option
+		key     map[string]interface{}
+		want    []int
+		wantErr string
+	}{
+		{"same partition", nil, map[string]interface{}{"PK": "P", "SK": "S#03"}, []int{2, 1}, ""},
+		{"another partition", nil, map[string]interface{}{"PK": "SECRET", "SK": "S#03"}, nil, "issued for another query"},
+		{"signing key", []dc.Option{dc.WithCursorSigningKey([]byte("secret"))}, map[string]interface{}{"PK": "P", "SK": "S#03"}, nil, "unsigned key map"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t, tt.options...)
+			insertTestRecords(t, client, 5)
+
+			got, _, err := dc.ListItemsAs[testRecord](client, dc.Key{PK: aws.String("P")}, "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: 2, LastEvaluatedKey: tt.key}})
+			if "" != tt.wantErr {
+				if !errors.Is(err, dc.ErrInvalidCursor) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ListItemsAs() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil || !reflect.DeepEqual(counts(got), tt.want) {
+				t.Errorf("ListItemsAs() = %v, %v, want %v", counts(got), err, tt.want)
+			}
+		})
+	}
+}
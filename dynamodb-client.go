@@ -2,14 +2,12 @@ package dynamodb_client
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"gitlab.com/ptami_lib/util"
-	"reflect"
+	"sort"
 	"strings"
 	"time"
 )
@@ -30,6 +28,8 @@ type QueryOption struct {
 	Order            []QueryOptionOrder     `json:"order"`
 	ScanIndexForward *bool                  `json:"scanIndexForward"`
 	Page             *QueryOptionPage       `json:"page" validate:"required"`
+	// Condition is a typed filter, joined with AND to Filter when both are set.
+	Condition Condition `json:"-"`
 }
 
 type Key struct {
@@ -49,81 +49,314 @@ const (
 	KeySortKeyTypeBeginsWith           = "begins_with"
 )
 
+// DynamoDbApi is the subset of *dynamodb.Client used by DynamoDbClient, so that
+// fakes, recorders or wrapped clients can be injected in its place.
+type DynamoDbApi interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+	TransactGetItems(ctx context.Context, params *dynamodb.TransactGetItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+var _ DynamoDbApi = (*dynamodb.Client)(nil)
+
 type DynamoDbClient struct {
-	dynamoDb  *dynamodb.Client
-	tableName string
+	dynamoDb         DynamoDbApi
+	tableName        string
+	retryPolicy      RetryPolicy
+	clock            Clock
+	idGenerator      IdGenerator
+	cursorSigningKey []byte
 }
 
-func New(dynamoDb *dynamodb.Client, tableName string) *DynamoDbClient {
-	return &DynamoDbClient{
-		dynamoDb:  dynamoDb,
-		tableName: tableName,
+func New(dynamoDb DynamoDbApi, tableName string, options ...Option) *DynamoDbClient {
+	r := &DynamoDbClient{
+		dynamoDb:    dynamoDb,
+		tableName:   tableName,
+		retryPolicy: DefaultRetryPolicy,
+		clock:       ClockFunc(time.Now),
+		idGenerator: UlidGenerator,
+	}
+
+	for _, option := range options {
+		option(r)
 	}
+
+	return r
 }
 
 func (r *DynamoDbClient) DeleteAllItem() (err error) {
+	return r.DeleteAllItemCtx(context.TODO())
+}
+
+func (r *DynamoDbClient) DeleteAllItemCtx(ctx context.Context) (err error) {
 	paginator := dynamodb.NewScanPaginator(r.dynamoDb, &dynamodb.ScanInput{
 		TableName: aws.String(r.tableName),
 	})
 
 	for paginator.HasMorePages() {
 		var out *dynamodb.ScanOutput
-		out, err = paginator.NextPage(context.TODO())
+
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		out, err = paginator.NextPage(ctx)
 		if err != nil {
 			return
 		}
 
-		for _, item := range out.Items {
-			_, err = r.dynamoDb.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
-				TableName: aws.String(r.tableName),
-				Key: map[string]types.AttributeValue{
+		requests := make([]batchWriteRequest, len(out.Items))
+		for i, item := range out.Items {
+			requests[i] = batchWriteRequest{
+				index: i,
+				key:   keyOfItem(item),
+				request: types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{
 					"PK": item["PK"],
 					"SK": item["SK"],
-				},
-			})
-			if err != nil {
-				return
+				}}},
 			}
 		}
+
+		err = r.batchWrite(ctx, requests, nil)
+		if err != nil {
+			return
+		}
 	}
 
 	return
 }
 
-func (r *DynamoDbClient) Insert(item interface{}) (err error) {
+// Insert puts item. When item embeds DynamoDbMetaData, its missing Id,
+// CreatedTimestamp and UpdatedTimestamp are filled in from the client's
+// IdGenerator and Clock, in place when item is a pointer.
+func (r *DynamoDbClient) Insert(item interface{}, options ...WriteOption) (err error) {
+	return r.InsertCtx(context.TODO(), item, options...)
+}
+
+func (r *DynamoDbClient) InsertCtx(ctx context.Context, item interface{}, options ...WriteOption) (err error) {
 	var avItem map[string]types.AttributeValue
+	var metaData *DynamoDbMetaData
+	var o = newWriteOption(options)
 
-	avItem, err = attributevalue.MarshalMap(item)
+	avItem, metaData, err = r.marshalItem(item)
 	if err != nil {
 		return
 	}
 
+	o.versionFromMetaData(metaData)
+	o.applyVersion(avItem)
+
 	input := &dynamodb.PutItemInput{
 		Item:      avItem,
 		TableName: aws.String(r.tableName),
 	}
 
-	_, err = r.dynamoDb.PutItem(context.TODO(), input)
+	input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues, err = o.buildCondition(true, nil, nil)
 	if err != nil {
 		return
 	}
 
+	_, err = r.dynamoDb.PutItem(ctx, input)
+	if err != nil {
+		err = o.wrapError(keyOfItem(avItem), err)
+		return
+	}
+
+	o.storeVersion(metaData)
+
 	return
 }
 
-func (r *DynamoDbClient) GetItemList(key Key, arrayOfField string, queryOption QueryOption) (items []map[string]types.AttributeValue, lastEvaluatedKey interface{}, err error) {
+// GetItemList queries the items of key.IndexName, or of the table when
+// IndexName is nil, matching key and queryOption. When more items remain,
+// lastEvaluatedKey is the Cursor to pass back as
+// QueryOptionPage.LastEvaluatedKey to read the next page. WithConsistentRead is
+// only valid without IndexName.
+func (r *DynamoDbClient) GetItemList(key Key, arrayOfField string, queryOption QueryOption, options ...ReadOption) (items []map[string]types.AttributeValue, lastEvaluatedKey Cursor, err error) {
+	return r.GetItemListCtx(context.TODO(), key, arrayOfField, queryOption, options...)
+}
+
+func (r *DynamoDbClient) GetItemListCtx(ctx context.Context, key Key, arrayOfField string, queryOption QueryOption, options ...ReadOption) (items []map[string]types.AttributeValue, lastEvaluatedKey Cursor, err error) {
 	var output *dynamodb.QueryOutput
-	var expressionAttributeValues map[string]types.AttributeValue
-	var keyConditionExpression string
+	var allInOne = nil != queryOption.Page && queryOption.Page.AllInOne
+
+	input, binding, sortOnClient, err := r.buildItemListInput(key, arrayOfField, queryOption, newReadOption(options))
+	if err != nil {
+		return
+	}
+
+	if sortOnClient {
+		if nil != queryOption.Page && !queryOption.Page.AllInOne {
+			err = &InvalidOrderError{Field: queryOption.Order[0].Field, Reason: "ordering by a field other than the index sort key requires AllInOne paging"}
+			return
+		}
+
+		allInOne = true
+	}
+
+	if nil != queryOption.Page && !queryOption.Page.AllInOne {
+		input.Limit = aws.Int32(int32(queryOption.Page.PageSize))
+
+		input.ExclusiveStartKey, err = r.decodeLastEvaluatedKey(binding, queryCursorPartition(key), queryOption.Page.LastEvaluatedKey)
+		if err != nil {
+			return
+		}
+	}
+
+query:
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	output, err = r.dynamoDb.Query(ctx, input)
+	if err != nil {
+		return
+	}
+
+	//if len(output.Items) < 1 {
+	//	err = errors.New(fmt.Sprintf("item not found (%s)", util.StructToString(key)))
+	//	return
+	//}
+
+	lastEvaluatedKey, err = r.encodeCursor(binding, output.LastEvaluatedKey)
+	if err != nil {
+		return
+	}
+
+	items = append(items, output.Items...)
+
+	if allInOne && nil != output.LastEvaluatedKey {
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+		goto query
+	}
+
+	if sortOnClient {
+		sortItems(items, queryOption.Order)
+	}
+
+	return
+}
+
+// GetCountList counts the items matching key and the filter of queryOption
+// across every page of the query, without reading them. count is the number of
+// matching items and scannedCount the number evaluated before the filter.
+func (r *DynamoDbClient) GetCountList(key Key, queryOption QueryOption, options ...ReadOption) (count int64, scannedCount int64, err error) {
+	return r.GetCountListCtx(context.TODO(), key, queryOption, options...)
+}
+
+func (r *DynamoDbClient) GetCountListCtx(ctx context.Context, key Key, queryOption QueryOption, options ...ReadOption) (count int64, scannedCount int64, err error) {
+	var output *dynamodb.QueryOutput
+
+	input, err := r.buildQueryInput(key, queryOption, newReadOption(options))
+	if err != nil {
+		return
+	}
+
+	input.Select = types.SelectCount
+
+query:
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	output, err = r.dynamoDb.Query(ctx, input)
+	if err != nil {
+		return
+	}
+
+	count += int64(output.Count)
+	scannedCount += int64(output.ScannedCount)
+
+	if nil != output.LastEvaluatedKey {
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+		goto query
+	}
+
+	return
+}
+
+// buildItemListInput builds the query of GetItemList and ItemIterator, without
+// paging, and reports whether queryOption.Order must be applied on the client.
+func (r *DynamoDbClient) buildItemListInput(key Key, arrayOfField string, queryOption QueryOption, o readOption) (input *dynamodb.QueryInput, binding string, sortOnClient bool, err error) {
 	var scanIndexForward = queryOption.ScanIndexForward
+	var projection = o.projectionOf(arrayOfField)
+
+	if len(queryOption.Order) > 0 {
+		var orderScanIndexForward *bool
+
+		orderScanIndexForward, sortOnClient, err = resolveQueryOptionOrder(aws.ToString(key.IndexName), queryOption.Order)
+		if err != nil {
+			return
+		}
+
+		if nil != orderScanIndexForward {
+			scanIndexForward = orderScanIndexForward
+		}
+	}
 
 	if nil == scanIndexForward {
 		scanIndexForward = aws.Bool(false)
 	}
 
+	input, err = r.buildQueryInput(key, queryOption, o)
+	if err != nil {
+		return
+	}
+
+	input.ScanIndexForward = scanIndexForward
+	binding = queryCursorBinding(key, input)
+
+	if len(projection) > 0 {
+		var projectionExpression string
+
+		projectionExpression, err = buildProjectionExpression(projection, input.ExpressionAttributeNames)
+		if err != nil {
+			return
+		}
+
+		input.ProjectionExpression = aws.String(projectionExpression)
+	}
+
+	return
+}
+
+// queryCursorBinding binds the cursors of a query to its index and key
+// condition.
+func queryCursorBinding(key Key, input *dynamodb.QueryInput) string {
+	return cursorBinding(aws.ToString(input.IndexName), aws.ToString(input.KeyConditionExpression), aws.ToString(key.PK), aws.ToString(key.SK))
+}
+
+// queryCursorPartition is the partition key a legacy key map passed as
+// QueryOptionPage.LastEvaluatedKey must hold.
+func queryCursorPartition(key Key) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		fmt.Sprintf("%sPK", aws.ToString(key.IndexName)): &types.AttributeValueMemberS{Value: aws.ToString(key.PK)},
+	}
+}
+
+// buildQueryInput builds the key condition, filter and consistency shared by
+// GetItemList and GetCountList. Without IndexName, the table is queried on its
+// own PK and SK.
+func (r *DynamoDbClient) buildQueryInput(key Key, queryOption QueryOption, o readOption) (input *dynamodb.QueryInput, err error) {
+	var expressionAttributeValues map[string]types.AttributeValue
+	var keyConditionExpression string
+	var indexName = aws.ToString(key.IndexName)
+
+	if o.consistentRead && nil != key.IndexName {
+		err = &ConsistentReadOnIndexError{Key: key}
+		return
+	}
+
 	expressionAttributeValues = make(map[string]types.AttributeValue)
 	expressionAttributeValues[":gsipk"] = &types.AttributeValueMemberS{Value: *key.PK}
-	keyConditionExpression = fmt.Sprintf("#%sPK = :gsipk", *key.IndexName)
+	keyConditionExpression = fmt.Sprintf("#%sPK = :gsipk", indexName)
 
 	if nil != key.SK {
 		var sortKeyConditionExpression string
@@ -148,18 +381,21 @@ func (r *DynamoDbClient) GetItemList(key Key, arrayOfField string, queryOption Q
 			var prefix string
 			var begin, end string
 
-			if strings.Contains(*key.SK, "#") {
-				var listPrefix = strings.Split(*key.SK, "#")
-				prefix = strings.Join(listPrefix[:len(listPrefix)-1], "#")
-				var listSection = strings.Split(listPrefix[len(listPrefix)-1], "/")
-				begin = fmt.Sprintf("%s#%s", prefix, listSection[0])
-				end = fmt.Sprintf("%s#%s", prefix, listSection[1])
+			var listPrefix = strings.Split(*key.SK, "#")
+			var listSection = strings.Split(listPrefix[len(listPrefix)-1], "/")
+			if len(listPrefix) < 2 || 2 != len(listSection) {
+				err = &InvalidKeyError{Key: key, Reason: "a between sort key must be prefix#start/end"}
+				return
 			}
 
+			prefix = strings.Join(listPrefix[:len(listPrefix)-1], "#")
+			begin = fmt.Sprintf("%s#%s", prefix, listSection[0])
+			end = fmt.Sprintf("%s#%s", prefix, listSection[1])
+
 			expressionAttributeValues[":gsiskBegin"] = &types.AttributeValueMemberS{Value: begin}
 			expressionAttributeValues[":gsiskEnd"] = &types.AttributeValueMemberS{Value: end}
 		default:
-			err = errors.New(fmt.Sprintf("not supported sort key type (%s)", sortKeyType))
+			err = &UnsupportedSortKeyTypeError{Key: key, SortKeyType: sortKeyType}
 			return
 		}
 
@@ -169,13 +405,13 @@ func (r *DynamoDbClient) GetItemList(key Key, arrayOfField string, queryOption Q
 			KeySortKeyTypeLessThan,
 			KeySortKeyTypeGreaterThanOrEqualTo,
 			KeySortKeyTypeGreaterThan:
-			sortKeyConditionExpression = fmt.Sprintf("#%sSK %s :gsisk", *key.IndexName, sortKeyType)
+			sortKeyConditionExpression = fmt.Sprintf("#%sSK %s :gsisk", indexName, sortKeyType)
 		case KeySortKeyTypeBetween:
-			sortKeyConditionExpression = fmt.Sprintf("#%sSK %s :gsiskBegin and :gsiskEnd", *key.IndexName, sortKeyType)
+			sortKeyConditionExpression = fmt.Sprintf("#%sSK %s :gsiskBegin and :gsiskEnd", indexName, sortKeyType)
 		case KeySortKeyTypeBeginsWith:
-			sortKeyConditionExpression = fmt.Sprintf("%s(#%sSK, :gsisk)", sortKeyType, *key.IndexName)
+			sortKeyConditionExpression = fmt.Sprintf("%s(#%sSK, :gsisk)", sortKeyType, indexName)
 		default:
-			err = errors.New(fmt.Sprintf("not supported sort key type (%s)", sortKeyType))
+			err = &UnsupportedSortKeyTypeError{Key: key, SortKeyType: sortKeyType}
 			return
 		}
 
@@ -183,144 +419,47 @@ func (r *DynamoDbClient) GetItemList(key Key, arrayOfField string, queryOption Q
 	}
 
 	expressionAttributeNames := make(map[string]string)
-	expressionAttributeNames[fmt.Sprintf("#%sPK", *key.IndexName)] = fmt.Sprintf("%sPK", *key.IndexName)
-	expressionAttributeNames[fmt.Sprintf("#%sSK", *key.IndexName)] = fmt.Sprintf("%sSK", *key.IndexName)
+	expressionAttributeNames[fmt.Sprintf("#%sPK", indexName)] = fmt.Sprintf("%sPK", indexName)
+	if nil != key.SK {
+		expressionAttributeNames[fmt.Sprintf("#%sSK", indexName)] = fmt.Sprintf("%sSK", indexName)
+	}
 
-	input := &dynamodb.QueryInput{
+	input = &dynamodb.QueryInput{
 		ExpressionAttributeNames:  expressionAttributeNames,
 		ExpressionAttributeValues: expressionAttributeValues,
 		KeyConditionExpression:    aws.String(keyConditionExpression),
 		TableName:                 aws.String(r.tableName),
-		ScanIndexForward:          scanIndexForward,
-	}
-
-	if nil != queryOption.Filter {
-		filterExpression, _ := processQueryOptionFilter(queryOption.Filter, expressionAttributeValues, expressionAttributeNames)
-		input.FilterExpression = aws.String(filterExpression)
-	}
-
-	if nil != queryOption.Page && !queryOption.Page.AllInOne {
-		input.Limit = aws.Int32(int32(queryOption.Page.PageSize))
-
-		if reflect.ValueOf(queryOption.Page.LastEvaluatedKey).Len() > 0 {
-			var _lastEvaluatedKey map[string]types.AttributeValue
-
-			_lastEvaluatedKey, err = attributevalue.MarshalMap(queryOption.Page.LastEvaluatedKey)
-			if err != nil {
-				return
-			}
-
-			input.ExclusiveStartKey = _lastEvaluatedKey
-		}
-	}
-
-	if key.IndexName != nil {
-		input.IndexName = key.IndexName
-	}
-
-	if arrayOfField != "" {
-		temp := strings.Split(arrayOfField, ",")
-		tempArrayOfField := make([]string, len(temp))
-
-		for i, v := range temp {
-			expressionAttributeNames[fmt.Sprintf("#%s", v)] = strings.ReplaceAll(v, "#", "")
-			tempArrayOfField[i] = fmt.Sprintf("#%s", v)
-		}
-
-		input.ProjectionExpression = aws.String(strings.Join(tempArrayOfField, ","))
-	}
-
-query:
-	output, err = r.dynamoDb.Query(context.TODO(), input)
-	if err != nil {
-		return
+		IndexName:                 key.IndexName,
+		ConsistentRead:            o.consistentReadInput(),
 	}
 
-	//if len(output.Items) < 1 {
-	//	err = errors.New(fmt.Sprintf("item not found (%s)", util.StructToString(key)))
-	//	return
-	//}
+	if nil != queryOption.Filter || nil != queryOption.Condition {
+		var filterExpression string
 
-	if nil != output.LastEvaluatedKey {
-		LastEvaluatedKey := new(map[string]interface{})
-		if err = attributevalue.UnmarshalMap(output.LastEvaluatedKey, &LastEvaluatedKey); err != nil {
+		filterExpression, err = processQueryOptionCondition(queryOption, expressionAttributeValues, expressionAttributeNames)
+		if err != nil {
 			return
 		}
-		lastEvaluatedKey = LastEvaluatedKey
-	}
-
-	items = append(items, output.Items...)
 
-	if nil != queryOption.Page && queryOption.Page.AllInOne && nil != output.LastEvaluatedKey {
-		goto query
+		if "" != filterExpression {
+			input.FilterExpression = aws.String(filterExpression)
+		}
 	}
 
 	return
 }
 
-//func (r *DynamoDbClient) GetCountList(key Key, arrayOfField string, queryOption QueryOption) (total uint64, err error) {
-//	var output *dynamodb.QueryOutput
-//	var expressionAttributeValues map[string]types.AttributeValue
-//	var keyConditionExpression string
-//
-//	expressionAttributeValues = make(map[string]types.AttributeValue)
-//	expressionAttributeValues[":gsipk"] = &types.AttributeValueMemberS{Value: *key.PK}
-//
-//	keyConditionExpression = fmt.Sprintf("#%sPK = :gsipk", *key.IndexName)
-//
-//	if nil != key.SK {
-//		expressionAttributeValues[":gsisk"] = &types.AttributeValueMemberS{Value: *key.SK}
-//		if strings.HasSuffix(*key.SK, "#") {
-//			keyConditionExpression = fmt.Sprintf("#%sPK = :gsipk and begins_with(#%sSK, :gsisk)", *key.IndexName, *key.IndexName)
-//		} else {
-//			keyConditionExpression = fmt.Sprintf("#%sPK = :gsipk and #%sSK = :gsisk", *key.IndexName, *key.IndexName)
-//		}
-//	}
-//
-//	expressionAttributeNames := make(map[string]string)
-//	expressionAttributeNames[fmt.Sprintf("#%sPK", *key.IndexName)] = fmt.Sprintf("%sPK", *key.IndexName)
-//	expressionAttributeNames[fmt.Sprintf("#%sSK", *key.IndexName)] = fmt.Sprintf("%sSK", *key.IndexName)
-//
-//	input := &dynamodb.QueryInput{
-//		ExpressionAttributeNames:  expressionAttributeNames,
-//		ExpressionAttributeValues: expressionAttributeValues,
-//		KeyConditionExpression:    aws.String(keyConditionExpression),
-//		TableName:                 aws.String(r.tableName),
-//	}
-//
-//	if queryOption.Filter != nil {
-//		filterExpression, _ := processQueryOptionFilter(queryOption.Filter, expressionAttributeValues, expressionAttributeNames)
-//		input.FilterExpression = aws.String(filterExpression)
-//	}
-//
-//	if key.IndexName != nil {
-//		input.IndexName = key.IndexName
-//	}
-//
-//	if arrayOfField != "" {
-//		temp := strings.Split(arrayOfField, ",")
-//		tempArrayOfField := make([]string, len(temp))
-//		for i, v := range temp {
-//			expressionAttributeNames[fmt.Sprintf("#%s", v)] = strings.ReplaceAll(v, "#", "")
-//			tempArrayOfField[i] = fmt.Sprintf("#%s", v)
-//		}
-//		input.ProjectionExpression = aws.String(strings.Join(tempArrayOfField, ","))
-//	}
-//
-//	output, err = r.dynamoDb.Query(context.TODO(), input)
-//	if err != nil {
-//		return
-//	}
-//	if len(output.Items) < 1 {
-//		total = 0
-//		return
-//	} else {
-//		total = uint64(len(output.Items))
-//	}
-//	return
-//}
-
-func (r *DynamoDbClient) GetItem(key Key) (item map[string]types.AttributeValue, err error) {
+// GetItem reads the item at key, or the first item matching key on the index
+// key.IndexName when set. WithProjection limits the attributes read and
+// WithConsistentRead, only valid without IndexName, forces a strongly
+// consistent read.
+func (r *DynamoDbClient) GetItem(key Key, options ...ReadOption) (item map[string]types.AttributeValue, err error) {
+	return r.GetItemCtx(context.TODO(), key, options...)
+}
+
+func (r *DynamoDbClient) GetItemCtx(ctx context.Context, key Key, options ...ReadOption) (item map[string]types.AttributeValue, err error) {
+	var o = newReadOption(options)
+
 	if nil == key.IndexName {
 		var av map[string]types.AttributeValue
 		var output *dynamodb.GetItemOutput
@@ -331,32 +470,43 @@ func (r *DynamoDbClient) GetItem(key Key) (item map[string]types.AttributeValue,
 		}
 
 		input := &dynamodb.GetItemInput{
-			Key:       av,
-			TableName: aws.String(r.tableName),
+			Key:            av,
+			TableName:      aws.String(r.tableName),
+			ConsistentRead: o.consistentReadInput(),
+		}
+
+		input.ProjectionExpression, input.ExpressionAttributeNames, err = o.buildProjection("")
+		if err != nil {
+			return
 		}
 
-		output, err = r.dynamoDb.GetItem(context.TODO(), input)
+		output, err = r.dynamoDb.GetItem(ctx, input)
 		if err != nil {
 			return
 		}
 
 		if nil == output.Item {
-			err = errors.New(fmt.Sprintf("item not found (%s)", util.StructToString(key)))
+			err = &NotFoundError{Key: key}
 			return
 		}
 
 		item = output.Item
 	} else {
-		item, err = r.getItemViaGsi(key)
+		item, err = r.getItemViaGsi(ctx, key, o)
 	}
 
 	return
 }
 
-func (r *DynamoDbClient) getItemViaGsi(key Key) (item map[string]types.AttributeValue, err error) {
+func (r *DynamoDbClient) getItemViaGsi(ctx context.Context, key Key, o readOption) (item map[string]types.AttributeValue, err error) {
 	var expressionAttributeValues map[string]types.AttributeValue
 	var keyConditionExpression string
 
+	if o.consistentRead {
+		err = &ConsistentReadOnIndexError{Key: key}
+		return
+	}
+
 	expressionAttributeValues = map[string]types.AttributeValue{
 		":gsipk": &types.AttributeValueMemberS{Value: *key.PK},
 	}
@@ -379,13 +529,18 @@ func (r *DynamoDbClient) getItemViaGsi(key Key) (item map[string]types.Attribute
 		Limit:                     aws.Int32(1),
 	}
 
-	output, err := r.dynamoDb.Query(context.TODO(), input)
+	input.ProjectionExpression, input.ExpressionAttributeNames, err = o.buildProjection("")
+	if err != nil {
+		return
+	}
+
+	output, err := r.dynamoDb.Query(ctx, input)
 	if err != nil {
 		return
 	}
 
 	if 0 == len(output.Items) {
-		err = errors.New(fmt.Sprintf("item not found (%s)", util.StructToString(key)))
+		err = &NotFoundError{Key: key}
 		return
 	}
 
@@ -394,8 +549,13 @@ func (r *DynamoDbClient) getItemViaGsi(key Key) (item map[string]types.Attribute
 	return
 }
 
-func (r *DynamoDbClient) DeleteItem(key Key) (err error) {
+func (r *DynamoDbClient) DeleteItem(key Key, options ...WriteOption) (err error) {
+	return r.DeleteItemCtx(context.TODO(), key, options...)
+}
+
+func (r *DynamoDbClient) DeleteItemCtx(ctx context.Context, key Key, options ...WriteOption) (err error) {
 	var av map[string]types.AttributeValue
+	var o = newWriteOption(options)
 
 	av, err = attributevalue.MarshalMap(key)
 	if err != nil {
@@ -407,195 +567,178 @@ func (r *DynamoDbClient) DeleteItem(key Key) (err error) {
 		TableName: aws.String(r.tableName),
 	}
 
-	_, err = r.dynamoDb.DeleteItem(context.TODO(), input)
+	input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues, err = o.buildCondition(false, nil, nil)
+	if err != nil {
+		return
+	}
+
+	_, err = r.dynamoDb.DeleteItem(ctx, input)
+	err = o.wrapError(key, err)
 
 	return
 }
 
-func (r *DynamoDbClient) UpdateItem(key Key, propertyMap map[string]interface{}) (output *dynamodb.UpdateItemOutput, err error) {
+func (r *DynamoDbClient) UpdateItem(key Key, propertyMap map[string]interface{}, options ...WriteOption) (output *dynamodb.UpdateItemOutput, err error) {
+	return r.UpdateItemCtx(context.TODO(), key, propertyMap, options...)
+}
+
+func (r *DynamoDbClient) UpdateItemCtx(ctx context.Context, key Key, propertyMap map[string]interface{}, options ...WriteOption) (output *dynamodb.UpdateItemOutput, err error) {
 	var keyAv map[string]types.AttributeValue
 	var expressionAv map[string]types.AttributeValue
-	var expressionAttributeNames = map[string]string{}
-	var expressionAttributeValues = map[string]interface{}{}
-	var expressionNamesAndValues = map[string]string{}
-	var updateExpressions []string
+	var expressionAttributeNames map[string]string
+	var updateExpression string
+	var o = newWriteOption(options)
 
 	keyAv, err = attributevalue.MarshalMap(key)
 	if err != nil {
 		return
 	}
 
-	// add UpdatedTimestamp
-	propertyMap["UpdatedTimestamp"] = time.Now()
-
-	err = buildExpressionAttributeNamesAndValue(nil, propertyMap, &expressionAttributeNames, &expressionAttributeValues, &expressionNamesAndValues)
-	if nil != err {
-		return
-	}
-
-	expressionAv, err = attributevalue.MarshalMap(expressionAttributeValues)
+	updateExpression, expressionAttributeNames, expressionAv, err = r.buildUpdateExpression(propertyMap, o)
 	if err != nil {
 		return
 	}
 
-	for k, v := range expressionNamesAndValues {
-		updateExpressions = append(updateExpressions, fmt.Sprintf("%s=%s", k, v))
-	}
-
 	input := &dynamodb.UpdateItemInput{
 		Key:                       keyAv,
 		TableName:                 aws.String(r.tableName),
 		ExpressionAttributeNames:  expressionAttributeNames,
 		ExpressionAttributeValues: expressionAv,
-		UpdateExpression:          aws.String(fmt.Sprintf("set %s", strings.Join(updateExpressions, ", "))),
+		UpdateExpression:          aws.String(updateExpression),
 		ReturnValues:              types.ReturnValueUpdatedNew,
 	}
 
-	output, err = r.dynamoDb.UpdateItem(context.TODO(), input)
+	input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues, err = o.buildCondition(false, expressionAttributeNames, expressionAv)
+	if err != nil {
+		return
+	}
+
+	output, err = r.dynamoDb.UpdateItem(ctx, input)
+	err = o.wrapError(key, err)
 
 	return
 }
 
-func buildExpressionAttributeNamesAndValue(parentName *[]string, mapData map[string]interface{}, expressionAttributeNames *map[string]string, expressionAttributeValues *map[string]interface{}, expressionNamesAndValues *map[string]string) (err error) {
-	for k, v := range mapData {
-		var isFunction = 0 == strings.Index(k, "Fn:")
-		var keysFunction []string
-
-		if isFunction {
-			keysFunction = strings.Split(k, ":") // Fn:function_name:key
-
-			if 3 == len(keysFunction) {
-				switch keysFunction[1] { // function_name
-				case "list_append":
-					k = keysFunction[2]
-					//v = fmt.Sprintf("list_append(%s, %s)",
-				case "increase", "decrease":
-					k = keysFunction[2]
-					(*expressionAttributeValues)[":_Zero"] = 0
-				default:
-					err = errors.New(fmt.Sprintf("unsupported function name (%s)", k))
-					return
-				}
-			} else {
-				err = errors.New(fmt.Sprintf("unsupported function format (%s)", k))
-				return
-			}
-		}
+// buildUpdateExpression converts the propertyMap of UpdateItem, including its
+// "Fn:function_name:key" properties, into a SET update expression listing its
+// paths in key order. propertyMap is left untouched.
+func (r *DynamoDbClient) buildUpdateExpression(propertyMap map[string]interface{}, o writeOption) (updateExpression string, expressionAttributeNames map[string]string, expressionAv map[string]types.AttributeValue, err error) {
+	var properties = make(map[string]interface{}, len(propertyMap)+2)
+	var updateExpressions []string
 
-		(*expressionAttributeNames)["#"+k] = k
+	expressionAttributeNames = map[string]string{}
+	expressionAv = map[string]types.AttributeValue{}
 
-		if !isFunction && reflect.ValueOf(v).Kind() == reflect.Map {
-			if nil == parentName {
-				parentName = &[]string{k}
-			} else {
-				if 1 <= len(*parentName) {
-					goto build
-				}
+	for k, v := range propertyMap {
+		properties[k] = v
+	}
 
-				_parentName := append(*parentName, k)
-				parentName = &_parentName
-			}
+	// add UpdatedTimestamp
+	properties["UpdatedTimestamp"] = r.clock.Now()
+	o.applyVersionToPropertyMap(properties)
 
-			err = buildExpressionAttributeNamesAndValue(parentName, v.(map[string]interface{}), expressionAttributeNames, expressionAttributeValues, expressionNamesAndValues)
-			if nil != err {
-				return
-			}
+	err = buildUpdateAssignments(newPlaceholderAllocator(expressionAttributeNames, expressionAv), "", properties, &updateExpressions)
+	if nil != err {
+		return
+	}
 
-			continue
-		}
+	updateExpression = fmt.Sprintf("set %s", strings.Join(updateExpressions, ", "))
 
-	build:
-		if nil == parentName {
-			(*expressionAttributeValues)[fmt.Sprintf(":%s", k)] = v
+	return
+}
 
-			if isFunction {
-				(*expressionNamesAndValues)[fmt.Sprintf("#%s", k)], err = buildFunctionForExpressionAttributeNamesAndValue(keysFunction[1], k)
-				if nil != err {
-					return
-				}
-			} else {
-				(*expressionNamesAndValues)[fmt.Sprintf("#%s", k)] = fmt.Sprintf(":%s", k)
-			}
-		} else {
-			(*expressionAttributeValues)[fmt.Sprintf(":%s", strings.Join(append(*parentName, k), "_"))] = v
+// buildProjectionExpression projects the document paths of projection, such as
+// "Name" or "Address.City", adding their names to expressionAttributeNames.
+func buildProjectionExpression(projection []string, expressionAttributeNames map[string]string) (projectionExpression string, err error) {
+	var a = newPlaceholderAllocator(expressionAttributeNames, nil)
+	var paths = make([]string, 0, len(projection))
 
-			if isFunction {
-				(*expressionNamesAndValues)[fmt.Sprintf("#%s", strings.Join(append(*parentName, k), ".#"))], err = buildFunctionForExpressionAttributeNamesAndValue(keysFunction[1], k)
-				if nil != err {
-					return
-				}
-			} else {
-				(*expressionNamesAndValues)[fmt.Sprintf("#%s", strings.Join(append(*parentName, k), ".#"))] = fmt.Sprintf(":%s", strings.Join(append(*parentName, k), "_"))
-			}
+	for _, v := range projection {
+		var path string
+
+		path, err = a.path(strings.TrimSpace(strings.ReplaceAll(v, "#", "")))
+		if err != nil {
+			return
+		}
+
+		if !containsString(paths, path) {
+			paths = append(paths, path)
 		}
 	}
 
+	projectionExpression = strings.Join(paths, ",")
+
 	return
 }
 
-func buildFunctionForExpressionAttributeNamesAndValue(functionName string, key string) (function string, err error) {
-	switch functionName {
-	case "list_append":
-		function = fmt.Sprintf("list_append(%s, :%s)", key, key)
-	case "increase":
-		function = fmt.Sprintf("if_not_exists(%s, :_Zero) + :%s", key, key)
-	case "decrease":
-		function = fmt.Sprintf("if_not_exists(%s, :_Zero) - :%s", key, key)
-	default:
-		err = errors.New(fmt.Sprintf("unsupported function name (%s)", functionName))
+// buildUpdateAssignments adds the SET assignments of mapData to assignments.
+// Every key of mapData is a document path, optionally written
+// "Fn:function_name:path" to apply list_append, increase or decrease; a map
+// value of a top level path is set entry by entry rather than replaced.
+func buildUpdateAssignments(a *placeholderAllocator, parentPath string, mapData map[string]interface{}, assignments *[]string) (err error) {
+	var keys = make([]string, 0, len(mapData))
+
+	for k := range mapData {
+		keys = append(keys, k)
 	}
 
-	return
-}
+	sort.Strings(keys)
 
-func processQueryOptionFilter(filter map[string]interface{}, expressionAttributeValues map[string]types.AttributeValue, expressionAttributeName map[string]string) (filterExpression string, err error) {
-	if nil == filter {
-		return
-	}
-	var i = 0
-	for _, value := range filter {
-		item := value.(map[string]interface{})
-		field := strings.ReplaceAll(item["field"].(string), ".", "")
-		filter := getWhere(item)
-		if i == 0 {
-			filterExpression += filter
-		} else {
-			filterExpression += " AND " + filter
+	for _, k := range keys {
+		var v = mapData[k]
+		var functionName string
+		var path string
+		var av types.AttributeValue
+
+		if 0 == strings.Index(k, "Fn:") {
+			keysFunction := strings.Split(k, ":") // Fn:function_name:key
+			if 3 != len(keysFunction) {
+				err = &UnsupportedFunctionError{Function: k}
+				return
+			}
+
+			functionName, k = keysFunction[1], keysFunction[2]
+		}
+
+		path, err = a.path(k)
+		if err != nil {
+			return
 		}
-		expressionAttributeName["#"+field] = item["field"].(string)
-		if item["type"].(string) == "date" {
-			date := strings.Split(item["keyword"].(string), "/")
-			expressionAttributeValues[":"+field+"Start"] = &types.AttributeValueMemberS{Value: date[0]}
-			expressionAttributeValues[":"+field+"End"] = &types.AttributeValueMemberS{Value: date[1]}
-		} else if item["type"].(string) != "exist" {
-			switch item["keyword"].(type) {
-			case bool:
-				expressionAttributeValues[":"+field] = &types.AttributeValueMemberBOOL{Value: item["keyword"].(bool)}
-			case string:
-				expressionAttributeValues[":"+field] = &types.AttributeValueMemberS{Value: item["keyword"].(string)}
+
+		if "" != parentPath {
+			path = fmt.Sprintf("%s.%s", parentPath, path)
+		}
+
+		// only the first level of nested maps is updated field by field
+		if nested, ok := v.(map[string]interface{}); ok && "" == functionName && "" == parentPath {
+			err = buildUpdateAssignments(a, path, nested, assignments)
+			if err != nil {
+				return
 			}
+
+			continue
 		}
-		i++
-	}
 
-	return
-}
+		av, err = attributevalue.Marshal(v)
+		if err != nil {
+			return
+		}
 
-func getWhere(item map[string]interface{}) (filterExpression string) {
-
-	field := strings.ReplaceAll(item["field"].(string), ".", "")
-	searchType := item["type"].(string)
-	field = strings.ReplaceAll(field, "\"", "'")
-
-	switch searchType {
-	case "keyword":
-		filterExpression = fmt.Sprintf("contains (#%s, :%s)", field, field)
-	case "const":
-		filterExpression = fmt.Sprintf("#%s = :%s", field, field)
-	case "date":
-		filterExpression = fmt.Sprintf("#%s BETWEEN :%s AND :%s", field, field+"Start", field+"End")
-	case "exist":
-		filterExpression = fmt.Sprintf("attribute_exists(#%v)", field)
+		var value = a.value(av)
+
+		switch functionName {
+		case "":
+			*assignments = append(*assignments, fmt.Sprintf("%s=%s", path, value))
+		case "list_append":
+			*assignments = append(*assignments, fmt.Sprintf("%s=list_append(%s, %s)", path, path, value))
+		case "increase":
+			*assignments = append(*assignments, fmt.Sprintf("%s=if_not_exists(%s, %s) + %s", path, path, a.zero(), value))
+		case "decrease":
+			*assignments = append(*assignments, fmt.Sprintf("%s=if_not_exists(%s, %s) - %s", path, path, a.zero(), value))
+		default:
+			err = &UnsupportedFunctionError{Function: functionName}
+			return
+		}
 	}
 
 	return
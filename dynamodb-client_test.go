@@ -0,0 +1,408 @@
+package dynamodb_client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	dc "gitlab.com/ptami_lib/dynamodb-client"
+	"gitlab.com/ptami_lib/dynamodb-client/dynamodbtest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+type testRecord struct {
+	dc.DynamoDbMetaData
+	Status string
+	Count  int
+	Tags   []string          `dynamodbav:",omitempty"`
+	Info   map[string]string `dynamodbav:",omitempty"`
+}
+
+func newTestClient(t *testing.T, options ...dc.Option) (*dc.DynamoDbClient, *dynamodbtest.Table) {
+	t.Helper()
+
+	table := dynamodbtest.New("t")
+	options = append([]dc.Option{
+		dc.WithClock(dc.ClockFunc(func() time.Time { return testNow })),
+		dc.WithIdGenerator(dc.IdGeneratorFunc(func(time.Time) (string, error) { return "id", nil })),
+	}, options...)
+
+	return dc.New(table, "t", options...), table
+}
+
+// insertTestRecords inserts count records of partition P, S#00 to S#<count-1>,
+// whose Status cycles through A, B and C.
+func insertTestRecords(t *testing.T, client *dc.DynamoDbClient, count int) {
+	t.Helper()
+
+	for i := 0; i < count; i++ {
+		record := testRecord{Status: []string{"A", "B", "C"}[i%3], Count: i}
+		record.PK = "P"
+		record.SK = fmt.Sprintf("S#%02d", i)
+		record.GSI1PK = aws.String("G")
+		record.GSI1SK = aws.String(fmt.Sprintf("G#%02d", i))
+
+		if err := client.Insert(record); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+}
+
+// recordingTable records the queries and counts the scans sent to the
+// in-memory table. When pageSize is set, it caps the pages of requests without
+// a Limit, standing in for the 1 MB page limit of DynamoDB; after, when set,
+// runs once every query or scan returned, e.g. to cancel a context between
+// pages.
+type recordingTable struct {
+	*dynamodbtest.Table
+	pageSize int32
+	queries  []dynamodb.QueryInput
+	scans    int
+	after    func()
+}
+
+func (r *recordingTable) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	r.queries = append(r.queries, *params)
+
+	if 0 != r.pageSize && nil == params.Limit {
+		params.Limit = aws.Int32(r.pageSize)
+	}
+
+	if nil != r.after {
+		defer r.after()
+	}
+
+	return r.Table.Query(ctx, params, optFns...)
+}
+
+func (r *recordingTable) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	r.scans++
+
+	if 0 != r.pageSize && nil == params.Limit {
+		params.Limit = aws.Int32(r.pageSize)
+	}
+
+	if nil != r.after {
+		defer r.after()
+	}
+
+	return r.Table.Scan(ctx, params, optFns...)
+}
+
+func testKey(sk string) dc.Key {
+	return dc.Key{PK: aws.String("P"), SK: aws.String(sk)}
+}
+
+// listKey queries the records of insertTestRecords through GSI1.
+func listKey() dc.Key {
+	return dc.Key{PK: aws.String("G"), IndexName: aws.String("GSI1")}
+}
+
+func counts(records []testRecord) (list []int) {
+	for _, record := range records {
+		list = append(list, record.Count)
+	}
+
+	return
+}
+
+func TestInsertAndGetItem(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	record := &testRecord{Status: "A", Count: 1}
+	record.PK, record.SK = "P", "S#00"
+	record.GSI1PK, record.GSI1SK = aws.String("G"), aws.String("G#00")
+
+	if err := client.Insert(record); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	if "id" != aws.ToString(record.Id) || nil == record.CreatedTimestamp || !testNow.Equal(*record.CreatedTimestamp) {
+		t.Errorf("Insert() did not fill the metadata: %+v", record.DynamoDbMetaData)
+	}
+
+	got, err := dc.GetItemAs[testRecord](client, testKey("S#00"))
+	if err != nil {
+		t.Fatalf("GetItemAs() error = %v", err)
+	}
+
+	if "A" != got.Status || 1 != got.Count || "id" != aws.ToString(got.Id) {
+		t.Errorf("GetItemAs() = %+v", got)
+	}
+
+	_, err = client.GetItem(testKey("S#99"))
+	if !errors.Is(err, dc.ErrNotFound) {
+		t.Errorf("GetItem() error = %v, want ErrNotFound", err)
+	}
+
+	_, err = dc.GetItemAs[testRecord](client, testKey("S#99"))
+	if !errors.Is(err, dc.ErrNotFound) || 1 != strings.Count(err.Error(), "S#99") {
+		t.Errorf("GetItemAs() error = %v, want ErrNotFound naming the key once", err)
+	}
+
+	got, err = dc.GetItemAs[testRecord](client, dc.Key{PK: aws.String("G"), SK: aws.String("G#00"), IndexName: aws.String("GSI1")})
+	if err != nil || "S#00" != got.SK {
+		t.Errorf("GetItemAs() on GSI1 = %+v, %v", got, err)
+	}
+}
+
+func TestGetItemListFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		queryOption dc.QueryOption
+		want        []int
+	}{
+		{"no filter", dc.QueryOption{}, []int{5, 4, 3, 2, 1, 0}},
+		{"forward", dc.QueryOption{ScanIndexForward: aws.Bool(true)}, []int{0, 1, 2, 3, 4, 5}},
+		{"const filter", dc.QueryOption{Filter: map[string]interface{}{
+			"status": map[string]interface{}{"field": "Status", "type": dc.FilterTypeConst, "keyword": "A"},
+		}}, []int{3, 0}},
+		{"or group", dc.QueryOption{Filter: map[string]interface{}{
+			"status": map[string]interface{}{"or": []interface{}{
+				map[string]interface{}{"field": "Status", "type": dc.FilterTypeConst, "keyword": "A"},
+				map[string]interface{}{"field": "Status", "type": dc.FilterTypeConst, "keyword": "B"},
+			}},
+		}}, []int{4, 3, 1, 0}},
+		{"not group", dc.QueryOption{Filter: map[string]interface{}{
+			"status": map[string]interface{}{"not": map[string]interface{}{"field": "Status", "type": dc.FilterTypeConst, "keyword": "A"}},
+		}}, []int{5, 4, 2, 1}},
+		{"condition", dc.QueryOption{Condition: dc.And(dc.Gte("Count", 2), dc.Ne("Status", "C"))}, []int{4, 3}},
+		{"filter and condition", dc.QueryOption{
+			Filter:    map[string]interface{}{"status": map[string]interface{}{"field": "Status", "type": dc.FilterTypeConst, "keyword": "B"}},
+			Condition: dc.Lt("Count", 3),
+		}, []int{1}},
+		{"between and in", dc.QueryOption{Condition: dc.Or(dc.Between("Count", 1, 2), dc.In("Count", 5))}, []int{5, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t)
+			insertTestRecords(t, client, 6)
+
+			got, _, err := dc.ListItemsAs[testRecord](client, listKey(), "", tt.queryOption)
+			if err != nil {
+				t.Fatalf("ListItemsAs() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(counts(got), tt.want) {
+				t.Errorf("ListItemsAs() = %v, want %v", counts(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestGetItemListInvalidFilter(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	_, _, err := client.GetItemList(listKey(), "", dc.QueryOption{Filter: map[string]interface{}{
+		"status": map[string]interface{}{"field": "Status", "type": "unknown", "keyword": "A"},
+	}})
+	if !errors.Is(err, dc.ErrInvalidFilter) {
+		t.Errorf("GetItemList() error = %v, want ErrInvalidFilter", err)
+	}
+}
+
+func TestUpdateItemFunctions(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	record := testRecord{Count: 5, Tags: []string{"a"}, Info: map[string]string{"City": "Paris"}}
+	record.PK, record.SK = "P", "S#00"
+
+	if err := client.Insert(record); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	propertyMap := map[string]interface{}{
+		"Status":              "B",
+		"Fn:increase:Count":   3,
+		"Fn:list_append:Tags": []string{"b"},
+		"Info":                map[string]interface{}{"Zip": "75000"},
+		"Fn:decrease:Missing": 2,
+	}
+
+	_, err := client.UpdateItem(testKey("S#00"), propertyMap)
+	if err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+
+	if 5 != len(propertyMap) {
+		t.Errorf("UpdateItem() modified propertyMap: %v", propertyMap)
+	}
+
+	got, err := client.GetItem(testKey("S#00"))
+	if err != nil {
+		t.Fatalf("GetItem() error = %v", err)
+	}
+
+	var decoded struct {
+		testRecord
+		Missing int
+	}
+
+	if err = attributevalue.UnmarshalMap(got, &decoded); err != nil {
+		t.Fatalf("unmarshal error = %v", err)
+	}
+
+	switch {
+	case "B" != decoded.Status:
+		t.Errorf("Status = %s, want B", decoded.Status)
+	case 8 != decoded.Count:
+		t.Errorf("Count = %d, want 8", decoded.Count)
+	case !reflect.DeepEqual([]string{"a", "b"}, decoded.Tags):
+		t.Errorf("Tags = %v, want [a b]", decoded.Tags)
+	case !reflect.DeepEqual(map[string]string{"City": "Paris", "Zip": "75000"}, decoded.Info):
+		t.Errorf("Info = %v, want City and Zip", decoded.Info)
+	case -2 != decoded.Missing:
+		t.Errorf("Missing = %d, want -2", decoded.Missing)
+	case nil == decoded.UpdatedTimestamp || !testNow.Equal(*decoded.UpdatedTimestamp):
+		t.Errorf("UpdatedTimestamp = %v, want %v", decoded.UpdatedTimestamp, testNow)
+	}
+
+	_, err = client.UpdateItem(testKey("S#00"), map[string]interface{}{"Fn:unknown:Count": 1})
+	if !errors.Is(err, dc.ErrUnsupportedFunction) {
+		t.Errorf("UpdateItem() error = %v, want ErrUnsupportedFunction", err)
+	}
+}
+
+func TestWriteConditions(t *testing.T) {
+	client, _ := newTestClient(t)
+	insertTestRecords(t, client, 1)
+
+	record := testRecord{Status: "Z"}
+	record.PK, record.SK = "P", "S#00"
+
+	err := client.Insert(record, dc.IfNotExists())
+	if !errors.Is(err, dc.ErrConditionFailed) {
+		t.Errorf("Insert(IfNotExists) error = %v, want ErrConditionFailed", err)
+	}
+
+	_, err = client.UpdateItem(testKey("S#01"), map[string]interface{}{"Status": "Z"}, dc.IfExists())
+	if !errors.Is(err, dc.ErrConditionFailed) {
+		t.Errorf("UpdateItem(IfExists) error = %v, want ErrConditionFailed", err)
+	}
+
+	_, err = client.UpdateItem(testKey("S#00"), map[string]interface{}{"Status": "Z"}, dc.WithCondition(dc.Eq("Status", "B")))
+	if !errors.Is(err, dc.ErrConditionFailed) {
+		t.Errorf("UpdateItem(WithCondition) error = %v, want ErrConditionFailed", err)
+	}
+
+	_, err = client.UpdateItem(testKey("S#00"), map[string]interface{}{"Status": "Z"}, dc.WithConditionFilter(map[string]interface{}{
+		"status": map[string]interface{}{"field": "Status", "type": "const", "keyword": "A"},
+	}))
+	if err != nil {
+		t.Errorf("UpdateItem(WithConditionFilter) error = %v", err)
+	}
+
+	err = client.DeleteItem(testKey("S#00"), dc.WithConditionFilter(map[string]interface{}{
+		"status": map[string]interface{}{"field": "Status", "type": "const", "keyword": "A"},
+	}))
+	if !errors.Is(err, dc.ErrConditionFailed) {
+		t.Errorf("DeleteItem(WithConditionFilter) error = %v, want ErrConditionFailed", err)
+	}
+
+	err = client.DeleteItem(testKey("S#00"), dc.WithCondition(dc.Exists("Missing")))
+	if !errors.Is(err, dc.ErrConditionFailed) {
+		t.Errorf("DeleteItem(WithCondition) error = %v, want ErrConditionFailed", err)
+	}
+
+	err = client.DeleteItem(testKey("S#00"), dc.WithCondition(dc.Eq("Status", "Z")))
+	if err != nil {
+		t.Errorf("DeleteItem(WithCondition) error = %v", err)
+	}
+}
+
+func TestGetCountList(t *testing.T) {
+	table := &recordingTable{Table: dynamodbtest.New("t"), pageSize: 2}
+	client := dc.New(table, "t")
+	insertTestRecords(t, client, 7)
+
+	count, scannedCount, err := client.GetCountList(listKey(), dc.QueryOption{Filter: map[string]interface{}{
+		"status": map[string]interface{}{"field": "Status", "type": "const", "keyword": "A"},
+	}})
+	if err != nil || 3 != count || 7 != scannedCount {
+		t.Errorf("GetCountList() = %d, %d, %v, want 3 and 7", count, scannedCount, err)
+	}
+
+	if 4 != len(table.queries) {
+		t.Errorf("GetCountList() sent %d queries, want 4", len(table.queries))
+	}
+
+	for _, input := range table.queries {
+		if types.SelectCount != input.Select {
+			t.Errorf("GetCountList() sent Select = %q, want COUNT", input.Select)
+		}
+	}
+
+	count, scannedCount, err = client.GetCountList(dc.Key{PK: aws.String("G"), SK: aws.String("G#"), IndexName: aws.String("GSI1"), SortKeyType: aws.String(dc.KeySortKeyTypeBeginsWith)}, dc.QueryOption{})
+	if err != nil || 7 != count || 7 != scannedCount {
+		t.Errorf("GetCountList() without filter = %d, %d, %v, want 7 and 7", count, scannedCount, err)
+	}
+}
+
+func TestBetweenSortKey(t *testing.T) {
+	client, _ := newTestClient(t)
+	insertTestRecords(t, client, 5)
+
+	key := listKey()
+	key.SK, key.SortKeyType = aws.String("G#01/03"), aws.String(dc.KeySortKeyTypeBetween)
+
+	got, _, err := dc.ListItemsAs[testRecord](client, key, "", dc.QueryOption{})
+	if err != nil || !reflect.DeepEqual(counts(got), []int{3, 2, 1}) {
+		t.Errorf("ListItemsAs() = %v, %v, want [3 2 1]", counts(got), err)
+	}
+
+	for _, sk := range []string{"G#01", "G01/03", "G#01/02/03"} {
+		key.SK = aws.String(sk)
+
+		if _, _, err = client.GetItemList(key, "", dc.QueryOption{}); !errors.Is(err, dc.ErrInvalidKey) {
+			t.Errorf("GetItemList() between %s error = %v, want ErrInvalidKey", sk, err)
+		}
+
+		if _, _, err = client.GetCountList(key, dc.QueryOption{}); !errors.Is(err, dc.ErrInvalidKey) {
+			t.Errorf("GetCountList() between %s error = %v, want ErrInvalidKey", sk, err)
+		}
+	}
+}
+
+func TestCanceledBetweenPages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	table := &recordingTable{Table: dynamodbtest.New("t"), pageSize: 2}
+	client := dc.New(table, "t")
+	insertTestRecords(t, client, 5)
+
+	table.after = cancel
+
+	_, _, err := client.GetItemListCtx(ctx, dc.Key{PK: aws.String("P")}, "", dc.QueryOption{Page: &dc.QueryOptionPage{AllInOne: true}})
+	if !errors.Is(err, context.Canceled) || 1 != len(table.queries) {
+		t.Errorf("GetItemListCtx() = %v after %d queries, want context.Canceled after 1", err, len(table.queries))
+	}
+
+	err = client.DeleteAllItemCtx(ctx)
+	if !errors.Is(err, context.Canceled) || 0 != table.scans {
+		t.Errorf("DeleteAllItemCtx() on a canceled context = %v after %d scans, want context.Canceled before any", err, table.scans)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	table.after = cancel
+
+	err = client.DeleteAllItemCtx(ctx)
+	if !errors.Is(err, context.Canceled) || 1 != table.scans {
+		t.Errorf("DeleteAllItemCtx() = %v after %d scans, want context.Canceled after 1", err, table.scans)
+	}
+
+	if 5 != len(table.Items()) {
+		t.Errorf("DeleteAllItemCtx() deleted items after the cancellation: %d left", len(table.Items()))
+	}
+}
@@ -0,0 +1,1109 @@
+package dynamodbtest
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenName
+	tokenValue
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(expression string) (tokens []token, err error) {
+	for i := 0; i < len(expression); {
+		c := expression[i]
+
+		switch {
+		case ' ' == c || '\t' == c || '\n' == c || '\r' == c:
+			i++
+		case '#' == c || ':' == c:
+			j := i + 1
+			for j < len(expression) && isIdentChar(expression[j]) {
+				j++
+			}
+
+			if j == i+1 {
+				err = validationError(fmt.Sprintf("invalid placeholder at position %d in expression (%s)", i, expression))
+				return
+			}
+
+			kind := tokenName
+			if ':' == c {
+				kind = tokenValue
+			}
+
+			tokens = append(tokens, token{kind: kind, text: expression[i:j]})
+			i = j
+		case isDigit(c):
+			j := i
+			for j < len(expression) && isDigit(expression[j]) {
+				j++
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: expression[i:j]})
+			i = j
+		case isIdentChar(c):
+			j := i
+			for j < len(expression) && isIdentChar(expression[j]) {
+				j++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, text: expression[i:j]})
+			i = j
+		case '<' == c || '>' == c:
+			if i+1 < len(expression) && ('=' == expression[i+1] || ('<' == c && '>' == expression[i+1])) {
+				tokens = append(tokens, token{kind: tokenSymbol, text: expression[i : i+2]})
+				i += 2
+			} else {
+				tokens = append(tokens, token{kind: tokenSymbol, text: expression[i : i+1]})
+				i++
+			}
+		case strings.IndexByte("()[],.=+-", c) >= 0:
+			tokens = append(tokens, token{kind: tokenSymbol, text: expression[i : i+1]})
+			i++
+		default:
+			err = validationError(fmt.Sprintf("invalid character %q at position %d in expression (%s)", c, i, expression))
+			return
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF})
+
+	return
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return isDigit(c) || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || '_' == c
+}
+
+// expressionAttributes resolves the placeholders of every expression in a
+// single request and records which of them were used, since DynamoDB rejects
+// requests that declare placeholders no expression refers to.
+type expressionAttributes struct {
+	names      map[string]string
+	values     map[string]types.AttributeValue
+	usedNames  map[string]bool
+	usedValues map[string]bool
+}
+
+func newExpressionAttributes(names map[string]string, values map[string]types.AttributeValue) *expressionAttributes {
+	return &expressionAttributes{
+		names:      names,
+		values:     values,
+		usedNames:  map[string]bool{},
+		usedValues: map[string]bool{},
+	}
+}
+
+func (r *expressionAttributes) name(placeholder string) (name string, err error) {
+	name, ok := r.names[placeholder]
+	if !ok {
+		err = validationError(fmt.Sprintf("An expression attribute name used in the document path is not defined; attribute name: %s", placeholder))
+		return
+	}
+
+	r.usedNames[placeholder] = true
+
+	return
+}
+
+func (r *expressionAttributes) value(placeholder string) (value types.AttributeValue, err error) {
+	value, ok := r.values[placeholder]
+	if !ok {
+		err = validationError(fmt.Sprintf("An expression attribute value used in expression is not defined; attribute value: %s", placeholder))
+		return
+	}
+
+	r.usedValues[placeholder] = true
+
+	return
+}
+
+func (r *expressionAttributes) checkUnused() (err error) {
+	var unused []string
+
+	for k := range r.names {
+		if !r.usedNames[k] {
+			unused = append(unused, k)
+		}
+	}
+
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		err = validationError(fmt.Sprintf("Value provided in ExpressionAttributeNames unused in expressions: keys: {%s}", strings.Join(unused, ", ")))
+		return
+	}
+
+	for k := range r.values {
+		if !r.usedValues[k] {
+			unused = append(unused, k)
+		}
+	}
+
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		err = validationError(fmt.Sprintf("Value provided in ExpressionAttributeValues unused in expressions: keys: {%s}", strings.Join(unused, ", ")))
+	}
+
+	return
+}
+
+type pathElement struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+type documentPath []pathElement
+
+func (p documentPath) String() string {
+	var sb strings.Builder
+
+	for i, e := range p {
+		if e.isIndex {
+			sb.WriteString(fmt.Sprintf("[%d]", e.index))
+		} else {
+			if i > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(e.name)
+		}
+	}
+
+	return sb.String()
+}
+
+type operand interface {
+	evaluate(item map[string]types.AttributeValue) (types.AttributeValue, bool)
+}
+
+type pathOperand struct {
+	path documentPath
+}
+
+func (o pathOperand) evaluate(item map[string]types.AttributeValue) (types.AttributeValue, bool) {
+	return resolvePath(item, o.path)
+}
+
+type valueOperand struct {
+	value types.AttributeValue
+}
+
+func (o valueOperand) evaluate(map[string]types.AttributeValue) (types.AttributeValue, bool) {
+	return o.value, true
+}
+
+type sizeOperand struct {
+	path documentPath
+}
+
+func (o sizeOperand) evaluate(item map[string]types.AttributeValue) (types.AttributeValue, bool) {
+	value, ok := resolvePath(item, o.path)
+	if !ok {
+		return nil, false
+	}
+
+	size, ok := attributeSize(value)
+	if !ok {
+		return nil, false
+	}
+
+	return &types.AttributeValueMemberN{Value: strconv.Itoa(size)}, true
+}
+
+type condition interface {
+	evaluate(item map[string]types.AttributeValue) bool
+}
+
+type andCondition struct {
+	left, right condition
+}
+
+func (c andCondition) evaluate(item map[string]types.AttributeValue) bool {
+	return c.left.evaluate(item) && c.right.evaluate(item)
+}
+
+type orCondition struct {
+	left, right condition
+}
+
+func (c orCondition) evaluate(item map[string]types.AttributeValue) bool {
+	return c.left.evaluate(item) || c.right.evaluate(item)
+}
+
+type notCondition struct {
+	inner condition
+}
+
+func (c notCondition) evaluate(item map[string]types.AttributeValue) bool {
+	return !c.inner.evaluate(item)
+}
+
+type comparisonCondition struct {
+	comparator  string
+	left, right operand
+}
+
+func (c comparisonCondition) evaluate(item map[string]types.AttributeValue) bool {
+	left, ok := c.left.evaluate(item)
+	if !ok {
+		return false
+	}
+
+	right, ok := c.right.evaluate(item)
+	if !ok {
+		return false
+	}
+
+	switch c.comparator {
+	case "=":
+		return equalValues(left, right)
+	case "<>":
+		return !equalValues(left, right)
+	}
+
+	cmp, ok := compareValues(left, right)
+	if !ok {
+		return false
+	}
+
+	switch c.comparator {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+type betweenCondition struct {
+	subject, low, high operand
+}
+
+func (c betweenCondition) evaluate(item map[string]types.AttributeValue) bool {
+	return comparisonCondition{comparator: ">=", left: c.subject, right: c.low}.evaluate(item) &&
+		comparisonCondition{comparator: "<=", left: c.subject, right: c.high}.evaluate(item)
+}
+
+type inCondition struct {
+	subject operand
+	list    []operand
+}
+
+func (c inCondition) evaluate(item map[string]types.AttributeValue) bool {
+	for _, o := range c.list {
+		if (comparisonCondition{comparator: "=", left: c.subject, right: o}).evaluate(item) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type functionCondition struct {
+	function string
+	path     documentPath
+	argument operand
+}
+
+func (c functionCondition) evaluate(item map[string]types.AttributeValue) bool {
+	value, exists := resolvePath(item, c.path)
+
+	switch c.function {
+	case "attribute_exists":
+		return exists
+	case "attribute_not_exists":
+		return !exists
+	}
+
+	if !exists {
+		return false
+	}
+
+	argument, ok := c.argument.evaluate(item)
+	if !ok {
+		return false
+	}
+
+	switch c.function {
+	case "attribute_type":
+		t, ok := argument.(*types.AttributeValueMemberS)
+		return ok && t.Value == attributeType(value)
+	case "begins_with":
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			a, ok := argument.(*types.AttributeValueMemberS)
+			return ok && strings.HasPrefix(v.Value, a.Value)
+		case *types.AttributeValueMemberB:
+			a, ok := argument.(*types.AttributeValueMemberB)
+			return ok && strings.HasPrefix(string(v.Value), string(a.Value))
+		}
+	case "contains":
+		return containsValue(value, argument)
+	}
+
+	return false
+}
+
+type parser struct {
+	tokens     []token
+	pos        int
+	attributes *expressionAttributes
+}
+
+func newParser(expression string, attributes *expressionAttributes) (p *parser, err error) {
+	var tokens []token
+
+	tokens, err = tokenize(expression)
+	if err != nil {
+		return
+	}
+
+	p = &parser{tokens: tokens, attributes: attributes}
+
+	return
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if tokenEOF != t.kind {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) isSymbol(symbol string) bool {
+	t := p.peek()
+	return tokenSymbol == t.kind && symbol == t.text
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return tokenIdent == t.kind && strings.EqualFold(keyword, t.text)
+}
+
+func (p *parser) isFunction(names ...string) bool {
+	t := p.peek()
+	if tokenIdent != t.kind || tokenSymbol != p.tokens[p.pos+1].kind || "(" != p.tokens[p.pos+1].text {
+		return false
+	}
+
+	for _, name := range names {
+		if name == t.text {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *parser) expectSymbol(symbol string) (err error) {
+	if !p.isSymbol(symbol) {
+		err = p.syntaxError(fmt.Sprintf("expected %q", symbol))
+		return
+	}
+
+	p.next()
+
+	return
+}
+
+func (p *parser) expectEOF() (err error) {
+	if tokenEOF != p.peek().kind {
+		err = p.syntaxError("unexpected trailing token")
+	}
+
+	return
+}
+
+func (p *parser) syntaxError(message string) error {
+	return validationError(fmt.Sprintf("Invalid expression: %s; token: %q", message, p.peek().text))
+}
+
+func (p *parser) parseCondition() (c condition, err error) {
+	var right condition
+
+	c, err = p.parseAnd()
+	if err != nil {
+		return
+	}
+
+	for p.isKeyword("OR") {
+		p.next()
+
+		right, err = p.parseAnd()
+		if err != nil {
+			return
+		}
+
+		c = orCondition{left: c, right: right}
+	}
+
+	return
+}
+
+func (p *parser) parseAnd() (c condition, err error) {
+	var right condition
+
+	c, err = p.parseNot()
+	if err != nil {
+		return
+	}
+
+	for p.isKeyword("AND") {
+		p.next()
+
+		right, err = p.parseNot()
+		if err != nil {
+			return
+		}
+
+		c = andCondition{left: c, right: right}
+	}
+
+	return
+}
+
+func (p *parser) parseNot() (c condition, err error) {
+	if p.isKeyword("NOT") {
+		p.next()
+
+		c, err = p.parseNot()
+		if err != nil {
+			return
+		}
+
+		c = notCondition{inner: c}
+
+		return
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (c condition, err error) {
+	if p.isSymbol("(") {
+		p.next()
+
+		c, err = p.parseCondition()
+		if err != nil {
+			return
+		}
+
+		err = p.expectSymbol(")")
+
+		return
+	}
+
+	if p.isFunction("attribute_exists", "attribute_not_exists", "attribute_type", "begins_with", "contains") {
+		return p.parseFunction()
+	}
+
+	var subject operand
+
+	subject, err = p.parseOperand()
+	if err != nil {
+		return
+	}
+
+	switch {
+	case p.isKeyword("BETWEEN"):
+		var low, high operand
+
+		p.next()
+
+		low, err = p.parseOperand()
+		if err != nil {
+			return
+		}
+
+		if !p.isKeyword("AND") {
+			err = p.syntaxError("expected AND in BETWEEN")
+			return
+		}
+
+		p.next()
+
+		high, err = p.parseOperand()
+		if err != nil {
+			return
+		}
+
+		c = betweenCondition{subject: subject, low: low, high: high}
+	case p.isKeyword("IN"):
+		var list []operand
+
+		p.next()
+
+		err = p.expectSymbol("(")
+		if err != nil {
+			return
+		}
+
+		for {
+			var o operand
+
+			o, err = p.parseOperand()
+			if err != nil {
+				return
+			}
+
+			list = append(list, o)
+
+			if !p.isSymbol(",") {
+				break
+			}
+
+			p.next()
+		}
+
+		err = p.expectSymbol(")")
+		if err != nil {
+			return
+		}
+
+		c = inCondition{subject: subject, list: list}
+	default:
+		t := p.peek()
+		if tokenSymbol != t.kind || !isComparator(t.text) {
+			err = p.syntaxError("expected comparator")
+			return
+		}
+
+		p.next()
+
+		var right operand
+
+		right, err = p.parseOperand()
+		if err != nil {
+			return
+		}
+
+		c = comparisonCondition{comparator: t.text, left: subject, right: right}
+	}
+
+	return
+}
+
+func isComparator(symbol string) bool {
+	switch symbol {
+	case "=", "<>", "<", "<=", ">", ">=":
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) parseFunction() (c condition, err error) {
+	var path documentPath
+
+	function := p.next().text
+	p.next()
+
+	path, err = p.parsePath()
+	if err != nil {
+		return
+	}
+
+	f := functionCondition{function: function, path: path}
+
+	if "attribute_exists" != function && "attribute_not_exists" != function {
+		err = p.expectSymbol(",")
+		if err != nil {
+			return
+		}
+
+		f.argument, err = p.parseOperand()
+		if err != nil {
+			return
+		}
+
+		if "attribute_type" == function {
+			if v, ok := f.argument.(valueOperand); ok {
+				if s, ok := v.value.(*types.AttributeValueMemberS); !ok || !isAttributeType(s.Value) {
+					err = validationError("Invalid attribute type name found in attribute_type function")
+					return
+				}
+			}
+		}
+	}
+
+	err = p.expectSymbol(")")
+	if err != nil {
+		return
+	}
+
+	c = f
+
+	return
+}
+
+func (p *parser) parseOperand() (o operand, err error) {
+	if p.isFunction("size") {
+		var path documentPath
+
+		p.next()
+		p.next()
+
+		path, err = p.parsePath()
+		if err != nil {
+			return
+		}
+
+		err = p.expectSymbol(")")
+		if err != nil {
+			return
+		}
+
+		o = sizeOperand{path: path}
+
+		return
+	}
+
+	if tokenValue == p.peek().kind {
+		var value types.AttributeValue
+
+		value, err = p.attributes.value(p.next().text)
+		if err != nil {
+			return
+		}
+
+		o = valueOperand{value: value}
+
+		return
+	}
+
+	var path documentPath
+
+	path, err = p.parsePath()
+	if err != nil {
+		return
+	}
+
+	o = pathOperand{path: path}
+
+	return
+}
+
+func (p *parser) parsePath() (path documentPath, err error) {
+	var name string
+
+	name, err = p.parseName()
+	if err != nil {
+		return
+	}
+
+	path = documentPath{{name: name}}
+
+	for {
+		switch {
+		case p.isSymbol("."):
+			p.next()
+
+			name, err = p.parseName()
+			if err != nil {
+				return
+			}
+
+			path = append(path, pathElement{name: name})
+		case p.isSymbol("["):
+			p.next()
+
+			t := p.next()
+			if tokenNumber != t.kind {
+				err = p.syntaxError("expected list index")
+				return
+			}
+
+			var index int
+
+			index, err = strconv.Atoi(t.text)
+			if err != nil {
+				err = p.syntaxError("invalid list index")
+				return
+			}
+
+			err = p.expectSymbol("]")
+			if err != nil {
+				return
+			}
+
+			path = append(path, pathElement{index: index, isIndex: true})
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) parseName() (name string, err error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenIdent:
+		name = t.text
+	case tokenName:
+		name, err = p.attributes.name(t.text)
+	default:
+		p.pos--
+		err = p.syntaxError("expected attribute name")
+	}
+
+	return
+}
+
+func parseCondition(expression string, attributes *expressionAttributes) (c condition, err error) {
+	var p *parser
+
+	p, err = newParser(expression, attributes)
+	if err != nil {
+		return
+	}
+
+	c, err = p.parseCondition()
+	if err != nil {
+		return
+	}
+
+	err = p.expectEOF()
+
+	return
+}
+
+func parseProjection(expression string, attributes *expressionAttributes) (paths []documentPath, err error) {
+	var p *parser
+
+	p, err = newParser(expression, attributes)
+	if err != nil {
+		return
+	}
+
+	for {
+		var path documentPath
+
+		path, err = p.parsePath()
+		if err != nil {
+			return
+		}
+
+		paths = append(paths, path)
+
+		if !p.isSymbol(",") {
+			break
+		}
+
+		p.next()
+	}
+
+	err = p.expectEOF()
+
+	return
+}
+
+type updateValue interface {
+	evaluate(item map[string]types.AttributeValue) (types.AttributeValue, error)
+}
+
+type operandValue struct {
+	operand operand
+}
+
+func (v operandValue) evaluate(item map[string]types.AttributeValue) (value types.AttributeValue, err error) {
+	value, ok := v.operand.evaluate(item)
+	if !ok {
+		err = validationError("The provided expression refers to an attribute that does not exist in the item")
+	}
+
+	return
+}
+
+type arithmeticValue struct {
+	operator    string
+	left, right updateValue
+}
+
+func (v arithmeticValue) evaluate(item map[string]types.AttributeValue) (value types.AttributeValue, err error) {
+	var left, right types.AttributeValue
+
+	left, err = v.left.evaluate(item)
+	if err != nil {
+		return
+	}
+
+	right, err = v.right.evaluate(item)
+	if err != nil {
+		return
+	}
+
+	return addNumbers(left, right, "-" == v.operator)
+}
+
+type ifNotExistsValue struct {
+	path     documentPath
+	fallback updateValue
+}
+
+func (v ifNotExistsValue) evaluate(item map[string]types.AttributeValue) (value types.AttributeValue, err error) {
+	value, ok := resolvePath(item, v.path)
+	if !ok {
+		value, err = v.fallback.evaluate(item)
+	}
+
+	return
+}
+
+type listAppendValue struct {
+	left, right updateValue
+}
+
+func (v listAppendValue) evaluate(item map[string]types.AttributeValue) (value types.AttributeValue, err error) {
+	var left, right types.AttributeValue
+
+	left, err = v.left.evaluate(item)
+	if err != nil {
+		return
+	}
+
+	right, err = v.right.evaluate(item)
+	if err != nil {
+		return
+	}
+
+	l, lok := left.(*types.AttributeValueMemberL)
+	r, rok := right.(*types.AttributeValueMemberL)
+	if !lok || !rok {
+		err = validationError("An operand in the update expression has an incorrect data type; list_append requires list operands")
+		return
+	}
+
+	list := make([]types.AttributeValue, 0, len(l.Value)+len(r.Value))
+	list = append(list, l.Value...)
+	list = append(list, r.Value...)
+	value = &types.AttributeValueMemberL{Value: list}
+
+	return
+}
+
+const (
+	updateActionSet    = "SET"
+	updateActionRemove = "REMOVE"
+	updateActionAdd    = "ADD"
+	updateActionDelete = "DELETE"
+)
+
+type updateAction struct {
+	action string
+	path   documentPath
+	value  updateValue
+}
+
+func parseUpdate(expression string, attributes *expressionAttributes) (actions []updateAction, err error) {
+	var p *parser
+
+	p, err = newParser(expression, attributes)
+	if err != nil {
+		return
+	}
+
+	seen := map[string]bool{}
+
+	for tokenEOF != p.peek().kind {
+		t := p.next()
+		action := strings.ToUpper(t.text)
+
+		if tokenIdent != t.kind || seen[action] {
+			p.pos--
+			err = p.syntaxError("expected update clause")
+			return
+		}
+
+		switch action {
+		case updateActionSet, updateActionRemove, updateActionAdd, updateActionDelete:
+		default:
+			p.pos--
+			err = p.syntaxError("expected update clause")
+			return
+		}
+
+		seen[action] = true
+
+		for {
+			var a updateAction
+
+			a, err = p.parseUpdateAction(action)
+			if err != nil {
+				return
+			}
+
+			actions = append(actions, a)
+
+			if !p.isSymbol(",") {
+				break
+			}
+
+			p.next()
+		}
+	}
+
+	if 0 == len(actions) {
+		err = validationError("Invalid UpdateExpression: The expression can not be empty")
+	}
+
+	return
+}
+
+func (p *parser) parseUpdateAction(action string) (a updateAction, err error) {
+	a.action = action
+
+	a.path, err = p.parsePath()
+	if err != nil {
+		return
+	}
+
+	switch action {
+	case updateActionSet:
+		err = p.expectSymbol("=")
+		if err != nil {
+			return
+		}
+
+		a.value, err = p.parseUpdateValue()
+	case updateActionAdd, updateActionDelete:
+		if tokenValue != p.peek().kind {
+			err = p.syntaxError("expected value placeholder")
+			return
+		}
+
+		var value types.AttributeValue
+
+		value, err = p.attributes.value(p.next().text)
+		if err != nil {
+			return
+		}
+
+		a.value = operandValue{operand: valueOperand{value: value}}
+	}
+
+	return
+}
+
+func (p *parser) parseUpdateValue() (v updateValue, err error) {
+	v, err = p.parseUpdateOperand()
+	if err != nil {
+		return
+	}
+
+	if p.isSymbol("+") || p.isSymbol("-") {
+		var right updateValue
+
+		operator := p.next().text
+
+		right, err = p.parseUpdateOperand()
+		if err != nil {
+			return
+		}
+
+		v = arithmeticValue{operator: operator, left: v, right: right}
+	}
+
+	return
+}
+
+func (p *parser) parseUpdateOperand() (v updateValue, err error) {
+	switch {
+	case p.isFunction("if_not_exists"):
+		var path documentPath
+		var fallback updateValue
+
+		p.next()
+		p.next()
+
+		path, err = p.parsePath()
+		if err != nil {
+			return
+		}
+
+		err = p.expectSymbol(",")
+		if err != nil {
+			return
+		}
+
+		fallback, err = p.parseUpdateOperand()
+		if err != nil {
+			return
+		}
+
+		err = p.expectSymbol(")")
+		if err != nil {
+			return
+		}
+
+		v = ifNotExistsValue{path: path, fallback: fallback}
+	case p.isFunction("list_append"):
+		var left, right updateValue
+
+		p.next()
+		p.next()
+
+		left, err = p.parseUpdateOperand()
+		if err != nil {
+			return
+		}
+
+		err = p.expectSymbol(",")
+		if err != nil {
+			return
+		}
+
+		right, err = p.parseUpdateOperand()
+		if err != nil {
+			return
+		}
+
+		err = p.expectSymbol(")")
+		if err != nil {
+			return
+		}
+
+		v = listAppendValue{left: left, right: right}
+	case p.isFunction("size"):
+		err = p.syntaxError("size is not supported in update expressions")
+	default:
+		var o operand
+
+		o, err = p.parseOperand()
+		if err != nil {
+			return
+		}
+
+		v = operandValue{operand: o}
+	}
+
+	return
+}
@@ -0,0 +1,245 @@
+package dynamodbtest
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func s(v string) types.AttributeValue {
+	return &types.AttributeValueMemberS{Value: v}
+}
+
+func n(v string) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: v}
+}
+
+func ss(v ...string) types.AttributeValue {
+	return &types.AttributeValueMemberSS{Value: v}
+}
+
+func l(v ...types.AttributeValue) types.AttributeValue {
+	return &types.AttributeValueMemberL{Value: v}
+}
+
+func m(v map[string]types.AttributeValue) types.AttributeValue {
+	return &types.AttributeValueMemberM{Value: v}
+}
+
+func testItem() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"PK":   s("P"),
+		"SK":   s("S#01"),
+		"Name": s("alice"),
+		"Age":  n("30"),
+		"Tags": ss("a", "b"),
+		"Info": m(map[string]types.AttributeValue{
+			"City":   s("Paris"),
+			"Scores": l(n("1"), n("2")),
+		}),
+	}
+}
+
+func testAttributes() *expressionAttributes {
+	return newExpressionAttributes(
+		map[string]string{"#n": "Name", "#i": "Info"},
+		map[string]types.AttributeValue{
+			":a":   s("alice"),
+			":p":   s("al"),
+			":t":   s("a"),
+			":x":   s("Paris"),
+			":ss":  s("SS"),
+			":xx":  s("XX"),
+			":b":   n("18"),
+			":c":   n("65"),
+			":one": n("1"),
+			":two": n("2"),
+		},
+	)
+}
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{"#n = :a", true},
+		{"Name = :a", true},
+		{"Name <> :a", false},
+		{"Age = :a", false},
+		{"Age < :b", false},
+		{"Age >= :b", true},
+		{"Age BETWEEN :b AND :c", true},
+		{"Name IN (:p, :a)", true},
+		{"Name IN (:p, :t)", false},
+		{"begins_with(Name, :p)", true},
+		{"begins_with(Name, :a)", true},
+		{"contains(Tags, :t)", true},
+		{"contains(Name, :x)", false},
+		{"attribute_exists(#i.City)", true},
+		{"attribute_not_exists(Missing)", true},
+		{"attribute_not_exists(#i.City)", false},
+		{"attribute_type(Tags, :ss)", true},
+		{"#i.City = :x", true},
+		{"#i.Scores[1] = :two", true},
+		{"#i.Scores[5] = :two", false},
+		{"size(#i.Scores) = :two", true},
+		{"size(Missing) = :two", false},
+		{"NOT Age < :b AND Name = :p", false},
+		{"Name = :p OR Name = :a AND Age < :b", false},
+		{"(Name = :p OR Name = :a) AND Age > :b", true},
+		{"NOT (Name = :p OR Name = :t)", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			c, err := parseCondition(tt.expression, testAttributes())
+			if err != nil {
+				t.Fatalf("parseCondition() error = %v", err)
+			}
+
+			if got := c.evaluate(testItem()); got != tt.want {
+				t.Errorf("evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConditionError(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"Name =", "expected attribute name"},
+		{"Name :a", "expected comparator"},
+		{"Name = :a)", "unexpected trailing token"},
+		{"Age BETWEEN :b :c", "expected AND in BETWEEN"},
+		{"#i.Scores[x] = :two", "expected list index"},
+		{"Name = :missing", "attribute value: :missing"},
+		{"#missing = :a", "attribute name: #missing"},
+		{"attribute_type(Tags, :xx)", "Invalid attribute type name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			_, err := parseCondition(tt.expression, testAttributes())
+			if nil == err || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseCondition() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckUnused(t *testing.T) {
+	attributes := newExpressionAttributes(
+		map[string]string{"#n": "Name", "#u": "Unused"},
+		map[string]types.AttributeValue{":a": s("alice")},
+	)
+
+	if _, err := parseCondition("#n = :a", attributes); err != nil {
+		t.Fatalf("parseCondition() error = %v", err)
+	}
+
+	err := attributes.checkUnused()
+	if nil == err || !strings.Contains(err.Error(), "#u") {
+		t.Errorf("checkUnused() error = %v, want unused #u", err)
+	}
+}
+
+func TestParseProjection(t *testing.T) {
+	paths, err := parseProjection("#i.City, Tags, #i.Scores[1]", testAttributes())
+	if err != nil {
+		t.Fatalf("parseProjection() error = %v", err)
+	}
+
+	want := map[string]types.AttributeValue{
+		"Tags": ss("a", "b"),
+		"Info": m(map[string]types.AttributeValue{
+			"City":   s("Paris"),
+			"Scores": l(n("2")),
+		}),
+	}
+
+	if got := projectItem(testItem(), paths); !reflect.DeepEqual(got, want) {
+		t.Errorf("projectItem() = %v, want %v", got, want)
+	}
+}
+
+func TestApplyUpdate(t *testing.T) {
+	tests := []struct {
+		expression string
+		values     map[string]types.AttributeValue
+		attribute  string
+		want       types.AttributeValue
+	}{
+		{"SET Age = Age + :one", nil, "Age", n("31")},
+		{"SET Age = Age - :one", nil, "Age", n("29")},
+		{"SET Nick = if_not_exists(Nick, :a)", nil, "Nick", s("alice")},
+		{"SET #n = if_not_exists(#n, :p)", nil, "Name", s("alice")},
+		{"SET #i.City = :p", nil, "Info", m(map[string]types.AttributeValue{"City": s("al"), "Scores": l(n("1"), n("2"))})},
+		{"SET #i.Scores = list_append(#i.Scores, :list)", map[string]types.AttributeValue{":list": l(n("3"))}, "Info", m(map[string]types.AttributeValue{"City": s("Paris"), "Scores": l(n("1"), n("2"), n("3"))})},
+		{"REMOVE Tags", nil, "Tags", nil},
+		{"ADD Tags :set", map[string]types.AttributeValue{":set": ss("c")}, "Tags", ss("a", "b", "c")},
+		{"DELETE Tags :set", map[string]types.AttributeValue{":set": ss("a")}, "Tags", ss("b")},
+		{"DELETE Tags :set", map[string]types.AttributeValue{":set": ss("a", "b")}, "Tags", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			attributes := testAttributes()
+			for k, v := range tt.values {
+				attributes.values[k] = v
+			}
+
+			actions, err := parseUpdate(tt.expression, attributes)
+			if err != nil {
+				t.Fatalf("parseUpdate() error = %v", err)
+			}
+
+			item := testItem()
+
+			updated, err := New("t").applyUpdate(map[string]types.AttributeValue{"PK": item["PK"], "SK": item["SK"]}, item, actions)
+			if err != nil {
+				t.Fatalf("applyUpdate() error = %v", err)
+			}
+
+			if got := updated[tt.attribute]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.attribute, got, tt.want)
+			}
+
+			if !reflect.DeepEqual(item, testItem()) {
+				t.Errorf("applyUpdate() modified the original item")
+			}
+		})
+	}
+}
+
+func TestApplyUpdateError(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"", "can not be empty"},
+		{"SET Age = :one SET Name = :a", "expected update clause"},
+		{"SET Count = Missing + :one", "does not exist"},
+		{"SET SK = :a", "part of the key"},
+		{"SET Names = list_append(Tags, :a)", "list_append"},
+		{"ADD Tags Name", "expected value placeholder"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			item := testItem()
+
+			actions, err := parseUpdate(tt.expression, testAttributes())
+			if nil == err {
+				_, err = New("t").applyUpdate(map[string]types.AttributeValue{"PK": item["PK"], "SK": item["SK"]}, item, actions)
+			}
+
+			if nil == err || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,1110 @@
+// Package dynamodbtest provides an in-memory DynamoDB table for running
+// dynamodb_client based code offline.
+//
+// The table follows the PK/SK + GSI layout of DynamoDbMetaData: the base table
+// is keyed by PK and SK, and every index NAME is keyed by NAMEPK and NAMESK.
+// Key conditions, filter, condition, projection and update expressions are
+// parsed and evaluated with DynamoDB semantics, including the rejection of
+// unused expression attribute names and values.
+package dynamodbtest
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	dynamodb_client "gitlab.com/ptami_lib/dynamodb-client"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	PartitionKey = "PK"
+	SortKey      = "SK"
+
+	maxBatchWriteItems    = 25
+	maxBatchGetItems      = 100
+	maxTransactionItems   = 100
+	transactionReasonNone = "None"
+)
+
+// DefaultIndexes are the global secondary indexes declared by
+// DynamoDbMetaData and DynamoDbValueMetaData.
+var DefaultIndexes = []string{"GSI1", "GSI2", "GSI3", "GSI4", "GSI5", "GSIV"}
+
+type Table struct {
+	mu        sync.Mutex
+	tableName string
+	indexes   map[string]bool
+	items     map[string]map[string]types.AttributeValue
+}
+
+var _ dynamodb_client.DynamoDbApi = (*Table)(nil)
+
+// New creates an empty table; DefaultIndexes are used when no index names are
+// given.
+func New(tableName string, indexNames ...string) *Table {
+	if 0 == len(indexNames) {
+		indexNames = DefaultIndexes
+	}
+
+	indexes := make(map[string]bool, len(indexNames))
+	for _, name := range indexNames {
+		indexes[name] = true
+	}
+
+	return &Table{
+		tableName: tableName,
+		indexes:   indexes,
+		items:     map[string]map[string]types.AttributeValue{},
+	}
+}
+
+// Items returns a copy of every stored item ordered by PK and SK.
+func (r *Table) Items() (items []map[string]types.AttributeValue) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, item := range r.sortedItems(PartitionKey, SortKey) {
+		items = append(items, copyItem(item))
+	}
+
+	return
+}
+
+func (r *Table) PutItem(_ context.Context, params *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (output *dynamodb.PutItemOutput, err error) {
+	var attributes *expressionAttributes
+	var cond condition
+	var key string
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = r.checkTable(params.TableName); err != nil {
+		return
+	}
+
+	if key, err = r.validateItem(params.Item); err != nil {
+		return
+	}
+
+	attributes = newExpressionAttributes(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+
+	if cond, err = parseOptionalCondition(params.ConditionExpression, attributes); err != nil {
+		return
+	}
+
+	if err = attributes.checkUnused(); err != nil {
+		return
+	}
+
+	old := r.items[key]
+
+	if err = checkCondition(cond, old); err != nil {
+		return
+	}
+
+	r.items[key] = copyItem(params.Item)
+
+	output = &dynamodb.PutItemOutput{}
+	if types.ReturnValueAllOld == params.ReturnValues {
+		output.Attributes = copyItem(old)
+	}
+
+	return
+}
+
+func (r *Table) GetItem(_ context.Context, params *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (output *dynamodb.GetItemOutput, err error) {
+	var attributes *expressionAttributes
+	var projection []documentPath
+	var key string
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = r.checkTable(params.TableName); err != nil {
+		return
+	}
+
+	if key, err = validateKey(params.Key); err != nil {
+		return
+	}
+
+	attributes = newExpressionAttributes(params.ExpressionAttributeNames, nil)
+
+	if projection, err = parseOptionalProjection(params.ProjectionExpression, attributes); err != nil {
+		return
+	}
+
+	if err = attributes.checkUnused(); err != nil {
+		return
+	}
+
+	output = &dynamodb.GetItemOutput{}
+	if item, ok := r.items[key]; ok {
+		output.Item = copyItem(projectItem(item, projection))
+	}
+
+	return
+}
+
+func (r *Table) Query(_ context.Context, params *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (output *dynamodb.QueryOutput, err error) {
+	var attributes *expressionAttributes
+	var keyCondition, filter condition
+	var projection []documentPath
+	var partitionKey, sortKey string
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = r.checkTable(params.TableName); err != nil {
+		return
+	}
+
+	if partitionKey, sortKey, err = r.indexKeys(params.IndexName, params.ConsistentRead); err != nil {
+		return
+	}
+
+	if nil == params.KeyConditionExpression {
+		err = validationError("Either the KeyConditions or KeyConditionExpression parameter must be specified in the request")
+		return
+	}
+
+	attributes = newExpressionAttributes(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+
+	if keyCondition, err = parseCondition(*params.KeyConditionExpression, attributes); err != nil {
+		return
+	}
+
+	if err = validateKeyCondition(keyCondition, partitionKey, sortKey); err != nil {
+		return
+	}
+
+	if filter, err = parseOptionalCondition(params.FilterExpression, attributes); err != nil {
+		return
+	}
+
+	if projection, err = parseOptionalProjection(params.ProjectionExpression, attributes); err != nil {
+		return
+	}
+
+	if err = attributes.checkUnused(); err != nil {
+		return
+	}
+
+	var candidates []map[string]types.AttributeValue
+
+	for _, item := range r.sortedItems(partitionKey, sortKey) {
+		if keyCondition.evaluate(item) {
+			candidates = append(candidates, item)
+		}
+	}
+
+	forward := nil == params.ScanIndexForward || *params.ScanIndexForward
+	if !forward {
+		for i, j := 0, len(candidates)-1; i < j; i, j = i+1, j-1 {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		}
+	}
+
+	if nil != params.ExclusiveStartKey && !keyCondition.evaluate(params.ExclusiveStartKey) {
+		err = validationError("The provided starting key is outside query boundaries based on provided conditions")
+		return
+	}
+
+	page, err := r.readPage(candidates, params.ExclusiveStartKey, forward, params.Limit, filter, projection, params.Select, partitionKey, sortKey)
+	if err != nil {
+		return
+	}
+
+	output = &dynamodb.QueryOutput{
+		Items:            page.items,
+		Count:            page.count,
+		ScannedCount:     page.scannedCount,
+		LastEvaluatedKey: page.lastEvaluatedKey,
+	}
+
+	return
+}
+
+func (r *Table) Scan(_ context.Context, params *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (output *dynamodb.ScanOutput, err error) {
+	var attributes *expressionAttributes
+	var filter condition
+	var projection []documentPath
+	var partitionKey, sortKey string
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = r.checkTable(params.TableName); err != nil {
+		return
+	}
+
+	if partitionKey, sortKey, err = r.indexKeys(params.IndexName, params.ConsistentRead); err != nil {
+		return
+	}
+
+	if (nil == params.Segment) != (nil == params.TotalSegments) {
+		err = validationError("Segment and TotalSegments must be specified together")
+		return
+	}
+
+	if nil != params.Segment && (*params.TotalSegments < 1 || *params.Segment < 0 || *params.Segment >= *params.TotalSegments) {
+		err = validationError("The Segment parameter must be non-negative and less than TotalSegments")
+		return
+	}
+
+	attributes = newExpressionAttributes(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+
+	if filter, err = parseOptionalCondition(params.FilterExpression, attributes); err != nil {
+		return
+	}
+
+	if projection, err = parseOptionalProjection(params.ProjectionExpression, attributes); err != nil {
+		return
+	}
+
+	if err = attributes.checkUnused(); err != nil {
+		return
+	}
+
+	var candidates []map[string]types.AttributeValue
+
+	for _, item := range r.sortedItems(partitionKey, sortKey) {
+		if nil != params.Segment && segmentOf(item[partitionKey], *params.TotalSegments) != *params.Segment {
+			continue
+		}
+
+		candidates = append(candidates, item)
+	}
+
+	page, err := r.readPage(candidates, params.ExclusiveStartKey, true, params.Limit, filter, projection, params.Select, partitionKey, sortKey)
+	if err != nil {
+		return
+	}
+
+	output = &dynamodb.ScanOutput{
+		Items:            page.items,
+		Count:            page.count,
+		ScannedCount:     page.scannedCount,
+		LastEvaluatedKey: page.lastEvaluatedKey,
+	}
+
+	return
+}
+
+func (r *Table) UpdateItem(_ context.Context, params *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (output *dynamodb.UpdateItemOutput, err error) {
+	var attributes *expressionAttributes
+	var cond condition
+	var actions []updateAction
+	var key string
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = r.checkTable(params.TableName); err != nil {
+		return
+	}
+
+	if key, err = validateKey(params.Key); err != nil {
+		return
+	}
+
+	if nil == params.UpdateExpression {
+		err = validationError("UpdateExpression must be specified")
+		return
+	}
+
+	attributes = newExpressionAttributes(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+
+	if actions, err = parseUpdate(*params.UpdateExpression, attributes); err != nil {
+		return
+	}
+
+	if cond, err = parseOptionalCondition(params.ConditionExpression, attributes); err != nil {
+		return
+	}
+
+	if err = attributes.checkUnused(); err != nil {
+		return
+	}
+
+	old := r.items[key]
+
+	if err = checkCondition(cond, old); err != nil {
+		return
+	}
+
+	updated, err := r.applyUpdate(params.Key, old, actions)
+	if err != nil {
+		return
+	}
+
+	r.items[key] = updated
+
+	output = &dynamodb.UpdateItemOutput{Attributes: returnValues(params.ReturnValues, old, updated, actions)}
+
+	return
+}
+
+func (r *Table) DeleteItem(_ context.Context, params *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (output *dynamodb.DeleteItemOutput, err error) {
+	var attributes *expressionAttributes
+	var cond condition
+	var key string
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err = r.checkTable(params.TableName); err != nil {
+		return
+	}
+
+	if key, err = validateKey(params.Key); err != nil {
+		return
+	}
+
+	attributes = newExpressionAttributes(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+
+	if cond, err = parseOptionalCondition(params.ConditionExpression, attributes); err != nil {
+		return
+	}
+
+	if err = attributes.checkUnused(); err != nil {
+		return
+	}
+
+	old := r.items[key]
+
+	if err = checkCondition(cond, old); err != nil {
+		return
+	}
+
+	delete(r.items, key)
+
+	output = &dynamodb.DeleteItemOutput{}
+	if types.ReturnValueAllOld == params.ReturnValues {
+		output.Attributes = copyItem(old)
+	}
+
+	return
+}
+
+func (r *Table) BatchGetItem(_ context.Context, params *dynamodb.BatchGetItemInput, _ ...func(*dynamodb.Options)) (output *dynamodb.BatchGetItemOutput, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	output = &dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]types.AttributeValue{},
+		UnprocessedKeys: map[string]types.KeysAndAttributes{},
+	}
+
+	total := 0
+
+	for tableName, request := range params.RequestItems {
+		var attributes *expressionAttributes
+		var projection []documentPath
+
+		if err = r.checkTable(aws.String(tableName)); err != nil {
+			return
+		}
+
+		total += len(request.Keys)
+		if total > maxBatchGetItems {
+			err = validationError(fmt.Sprintf("Too many items requested for the BatchGetItem call; limit is %d", maxBatchGetItems))
+			return
+		}
+
+		attributes = newExpressionAttributes(request.ExpressionAttributeNames, nil)
+
+		if projection, err = parseOptionalProjection(request.ProjectionExpression, attributes); err != nil {
+			return
+		}
+
+		if err = attributes.checkUnused(); err != nil {
+			return
+		}
+
+		seen := map[string]bool{}
+		responses := []map[string]types.AttributeValue{}
+
+		for _, k := range request.Keys {
+			var key string
+
+			if key, err = validateKey(k); err != nil {
+				return
+			}
+
+			if seen[key] {
+				err = validationError("Provided list of item keys contains duplicates")
+				return
+			}
+
+			seen[key] = true
+
+			if item, ok := r.items[key]; ok {
+				responses = append(responses, copyItem(projectItem(item, projection)))
+			}
+		}
+
+		output.Responses[tableName] = responses
+	}
+
+	return
+}
+
+func (r *Table) BatchWriteItem(_ context.Context, params *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (output *dynamodb.BatchWriteItemOutput, err error) {
+	type write struct {
+		key  string
+		item map[string]types.AttributeValue
+	}
+
+	var writes []write
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := map[string]bool{}
+
+	for tableName, requests := range params.RequestItems {
+		if err = r.checkTable(aws.String(tableName)); err != nil {
+			return
+		}
+
+		for _, request := range requests {
+			var w write
+
+			switch {
+			case nil != request.PutRequest && nil == request.DeleteRequest:
+				w.key, err = r.validateItem(request.PutRequest.Item)
+				w.item = request.PutRequest.Item
+			case nil != request.DeleteRequest && nil == request.PutRequest:
+				w.key, err = validateKey(request.DeleteRequest.Key)
+			default:
+				err = validationError("Supplied WriteRequest must contain exactly one of PutRequest or DeleteRequest")
+			}
+
+			if err != nil {
+				return
+			}
+
+			if seen[w.key] {
+				err = validationError("Provided list of item keys contains duplicates")
+				return
+			}
+
+			seen[w.key] = true
+			writes = append(writes, w)
+		}
+	}
+
+	if 0 == len(writes) || len(writes) > maxBatchWriteItems {
+		err = validationError(fmt.Sprintf("Member must have length between 1 and %d", maxBatchWriteItems))
+		return
+	}
+
+	for _, w := range writes {
+		if nil == w.item {
+			delete(r.items, w.key)
+		} else {
+			r.items[w.key] = copyItem(w.item)
+		}
+	}
+
+	output = &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]types.WriteRequest{}}
+
+	return
+}
+
+func (r *Table) TransactGetItems(_ context.Context, params *dynamodb.TransactGetItemsInput, _ ...func(*dynamodb.Options)) (output *dynamodb.TransactGetItemsOutput, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if 0 == len(params.TransactItems) || len(params.TransactItems) > maxTransactionItems {
+		err = validationError(fmt.Sprintf("Member must have length between 1 and %d", maxTransactionItems))
+		return
+	}
+
+	output = &dynamodb.TransactGetItemsOutput{}
+
+	for _, transactItem := range params.TransactItems {
+		var attributes *expressionAttributes
+		var projection []documentPath
+		var key string
+
+		get := transactItem.Get
+		if nil == get {
+			err = validationError("Supplied TransactGetItem must contain Get")
+			return
+		}
+
+		if err = r.checkTable(get.TableName); err != nil {
+			return
+		}
+
+		if key, err = validateKey(get.Key); err != nil {
+			return
+		}
+
+		attributes = newExpressionAttributes(get.ExpressionAttributeNames, nil)
+
+		if projection, err = parseOptionalProjection(get.ProjectionExpression, attributes); err != nil {
+			return
+		}
+
+		if err = attributes.checkUnused(); err != nil {
+			return
+		}
+
+		response := types.ItemResponse{}
+		if item, ok := r.items[key]; ok {
+			response.Item = copyItem(projectItem(item, projection))
+		}
+
+		output.Responses = append(output.Responses, response)
+	}
+
+	return
+}
+
+func (r *Table) TransactWriteItems(_ context.Context, params *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (output *dynamodb.TransactWriteItemsOutput, err error) {
+	type write struct {
+		key     string
+		keyAv   map[string]types.AttributeValue
+		cond    condition
+		item    map[string]types.AttributeValue
+		actions []updateAction
+		delete  bool
+		check   bool
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if 0 == len(params.TransactItems) || len(params.TransactItems) > maxTransactionItems {
+		err = validationError(fmt.Sprintf("Member must have length between 1 and %d", maxTransactionItems))
+		return
+	}
+
+	writes := make([]write, len(params.TransactItems))
+	seen := map[string]bool{}
+
+	for i, transactItem := range params.TransactItems {
+		var attributes *expressionAttributes
+		var tableName, conditionExpression *string
+		var w write
+
+		switch {
+		case nil != transactItem.Put:
+			op := transactItem.Put
+			tableName, conditionExpression = op.TableName, op.ConditionExpression
+			attributes = newExpressionAttributes(op.ExpressionAttributeNames, op.ExpressionAttributeValues)
+
+			if w.key, err = r.validateItem(op.Item); err != nil {
+				return
+			}
+
+			w.item = op.Item
+		case nil != transactItem.Update:
+			op := transactItem.Update
+			tableName, conditionExpression = op.TableName, op.ConditionExpression
+			attributes = newExpressionAttributes(op.ExpressionAttributeNames, op.ExpressionAttributeValues)
+
+			if w.key, err = validateKey(op.Key); err != nil {
+				return
+			}
+
+			if nil == op.UpdateExpression {
+				err = validationError("UpdateExpression must be specified")
+				return
+			}
+
+			if w.actions, err = parseUpdate(*op.UpdateExpression, attributes); err != nil {
+				return
+			}
+
+			w.keyAv = op.Key
+		case nil != transactItem.Delete:
+			op := transactItem.Delete
+			tableName, conditionExpression = op.TableName, op.ConditionExpression
+			attributes = newExpressionAttributes(op.ExpressionAttributeNames, op.ExpressionAttributeValues)
+
+			if w.key, err = validateKey(op.Key); err != nil {
+				return
+			}
+
+			w.delete = true
+		case nil != transactItem.ConditionCheck:
+			op := transactItem.ConditionCheck
+			tableName, conditionExpression = op.TableName, op.ConditionExpression
+			attributes = newExpressionAttributes(op.ExpressionAttributeNames, op.ExpressionAttributeValues)
+
+			if w.key, err = validateKey(op.Key); err != nil {
+				return
+			}
+
+			if nil == conditionExpression {
+				err = validationError("ConditionExpression must be specified for ConditionCheck")
+				return
+			}
+
+			w.check = true
+		default:
+			err = validationError("Supplied TransactWriteItem must contain exactly one operation")
+			return
+		}
+
+		if err = r.checkTable(tableName); err != nil {
+			return
+		}
+
+		if w.cond, err = parseOptionalCondition(conditionExpression, attributes); err != nil {
+			return
+		}
+
+		if err = attributes.checkUnused(); err != nil {
+			return
+		}
+
+		if seen[w.key] {
+			err = validationError("Transaction request cannot include multiple operations on one item")
+			return
+		}
+
+		seen[w.key] = true
+		writes[i] = w
+	}
+
+	reasons := make([]types.CancellationReason, len(writes))
+	cancelled := false
+
+	for i, w := range writes {
+		reasons[i] = types.CancellationReason{Code: aws.String(transactionReasonNone)}
+
+		if nil != w.cond && !w.cond.evaluate(emptyIfNil(r.items[w.key])) {
+			reasons[i] = types.CancellationReason{
+				Code:    aws.String("ConditionalCheckFailed"),
+				Message: aws.String("The conditional request failed"),
+			}
+			cancelled = true
+		}
+	}
+
+	if cancelled {
+		var codes []string
+		for _, reason := range reasons {
+			codes = append(codes, *reason.Code)
+		}
+
+		err = &types.TransactionCanceledException{
+			Message:             aws.String(fmt.Sprintf("Transaction cancelled, please refer cancellation reasons for specific reasons [%s]", strings.Join(codes, ", "))),
+			CancellationReasons: reasons,
+		}
+
+		return
+	}
+
+	results := make([]map[string]types.AttributeValue, len(writes))
+
+	for i, w := range writes {
+		if nil != w.actions {
+			if results[i], err = r.applyUpdate(w.keyAv, r.items[w.key], w.actions); err != nil {
+				return
+			}
+		}
+	}
+
+	for i, w := range writes {
+		switch {
+		case w.check:
+		case w.delete:
+			delete(r.items, w.key)
+		case nil != w.item:
+			r.items[w.key] = copyItem(w.item)
+		default:
+			r.items[w.key] = results[i]
+		}
+	}
+
+	output = &dynamodb.TransactWriteItemsOutput{}
+
+	return
+}
+
+func (r *Table) checkTable(tableName *string) (err error) {
+	if nil == tableName || *tableName != r.tableName {
+		err = &types.ResourceNotFoundException{Message: aws.String("Requested resource not found")}
+	}
+
+	return
+}
+
+// indexKeys returns the key attribute names of the base table or of the
+// given index.
+func (r *Table) indexKeys(indexName *string, consistentRead *bool) (partitionKey string, sortKey string, err error) {
+	if nil == indexName {
+		return PartitionKey, SortKey, nil
+	}
+
+	if !r.indexes[*indexName] {
+		err = validationError(fmt.Sprintf("The table does not have the specified index: %s", *indexName))
+		return
+	}
+
+	if nil != consistentRead && *consistentRead {
+		err = validationError("Consistent reads are not supported on global secondary indexes")
+		return
+	}
+
+	return *indexName + PartitionKey, *indexName + SortKey, nil
+}
+
+func (r *Table) validateItem(item map[string]types.AttributeValue) (key string, err error) {
+	if key, err = baseKey(item); err != nil {
+		return
+	}
+
+	for name := range r.indexes {
+		for _, attribute := range []string{name + PartitionKey, name + SortKey} {
+			if value, ok := item[attribute]; ok {
+				if s, isS := value.(*types.AttributeValueMemberS); !isS || "" == s.Value {
+					err = validationError(fmt.Sprintf("One or more parameter values were invalid: Type mismatch for Index Key %s Expected: S", attribute))
+					return
+				}
+			}
+		}
+	}
+
+	return
+}
+
+func (r *Table) applyUpdate(keyAv map[string]types.AttributeValue, old map[string]types.AttributeValue, actions []updateAction) (updated map[string]types.AttributeValue, err error) {
+	updated = copyItem(old)
+	if nil == updated {
+		updated = copyItem(keyAv)
+	}
+
+	// every value is computed against the item as it was before the update
+	values := make([]types.AttributeValue, len(actions))
+	for i, a := range actions {
+		if updateActionSet == a.action {
+			if values[i], err = a.value.evaluate(emptyIfNil(old)); err != nil {
+				return
+			}
+		}
+	}
+
+	for i, a := range actions {
+		if PartitionKey == a.path[0].name || SortKey == a.path[0].name {
+			err = validationError(fmt.Sprintf("Cannot update attribute %s. This attribute is part of the key", a.path[0].name))
+			return
+		}
+
+		switch a.action {
+		case updateActionSet:
+			err = setPath(updated, a.path, copyValue(values[i]))
+		case updateActionRemove:
+			removePath(updated, a.path)
+		case updateActionAdd:
+			var value, result types.AttributeValue
+
+			value, _ = a.value.evaluate(nil)
+			current, exists := resolvePath(updated, a.path)
+
+			if result, err = addToSet(current, exists, value); err == nil {
+				err = setPath(updated, a.path, result)
+			}
+		case updateActionDelete:
+			var value, result types.AttributeValue
+			var keep bool
+
+			value, _ = a.value.evaluate(nil)
+
+			if current, exists := resolvePath(updated, a.path); exists {
+				if result, keep, err = deleteFromSet(current, value); err == nil {
+					if keep {
+						err = setPath(updated, a.path, result)
+					} else {
+						removePath(updated, a.path)
+					}
+				}
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	if _, err = r.validateItem(updated); err != nil {
+		return
+	}
+
+	return
+}
+
+type page struct {
+	items            []map[string]types.AttributeValue
+	count            int32
+	scannedCount     int32
+	lastEvaluatedKey map[string]types.AttributeValue
+}
+
+func (r *Table) readPage(candidates []map[string]types.AttributeValue, exclusiveStartKey map[string]types.AttributeValue, forward bool, limit *int32, filter condition, projection []documentPath, selectType types.Select, partitionKey string, sortKey string) (p page, err error) {
+	if nil != limit && *limit < 1 {
+		err = validationError("Limit must be greater than or equal to 1")
+		return
+	}
+
+	start := 0
+
+	if nil != exclusiveStartKey {
+		_, hasPartitionKey := exclusiveStartKey[partitionKey]
+		_, hasSortKey := exclusiveStartKey[sortKey]
+
+		if _, err = baseKey(exclusiveStartKey); err != nil || !hasPartitionKey || !hasSortKey {
+			err = validationError("The provided starting key is invalid")
+			return
+		}
+
+		// resume after the position of the start key, which may no longer
+		// be among the candidates
+		start = sort.Search(len(candidates), func(i int) bool {
+			cmp := compareItemKeys(candidates[i], exclusiveStartKey, partitionKey, sortKey)
+			if !forward {
+				cmp = -cmp
+			}
+
+			return cmp > 0
+		})
+	}
+
+	if types.SelectCount != selectType {
+		p.items = []map[string]types.AttributeValue{}
+	}
+
+	for i := start; i < len(candidates); i++ {
+		item := candidates[i]
+
+		if nil != limit && p.scannedCount == *limit {
+			last := candidates[i-1]
+			p.lastEvaluatedKey = map[string]types.AttributeValue{}
+
+			for _, attribute := range []string{PartitionKey, SortKey, partitionKey, sortKey} {
+				if value, ok := last[attribute]; ok {
+					p.lastEvaluatedKey[attribute] = copyValue(value)
+				}
+			}
+
+			break
+		}
+
+		p.scannedCount++
+
+		if nil != filter && !filter.evaluate(item) {
+			continue
+		}
+
+		p.count++
+
+		if types.SelectCount != selectType {
+			p.items = append(p.items, copyItem(projectItem(item, projection)))
+		}
+	}
+
+	return
+}
+
+// sortedItems returns the items carrying both key attributes, ordered by
+// partition key, sort key and then by the base table key.
+func (r *Table) sortedItems(partitionKey string, sortKey string) (items []map[string]types.AttributeValue) {
+	for _, item := range r.items {
+		_, hasPartitionKey := item[partitionKey]
+		_, hasSortKey := item[sortKey]
+
+		if hasPartitionKey && hasSortKey {
+			items = append(items, item)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return compareItemKeys(items[i], items[j], partitionKey, sortKey) < 0
+	})
+
+	return
+}
+
+// compareItemKeys orders items by the keys of the index, then by the keys of
+// the table.
+func compareItemKeys(left, right map[string]types.AttributeValue, partitionKey string, sortKey string) int {
+	for _, attribute := range []string{partitionKey, sortKey, PartitionKey, SortKey} {
+		if cmp, _ := compareValues(left[attribute], right[attribute]); 0 != cmp {
+			return cmp
+		}
+	}
+
+	return 0
+}
+
+func validateKey(key map[string]types.AttributeValue) (itemKey string, err error) {
+	if 2 != len(key) {
+		err = validationError("The provided key element does not match the schema")
+		return
+	}
+
+	var parts []string
+
+	for _, attribute := range []string{PartitionKey, SortKey} {
+		s, ok := key[attribute].(*types.AttributeValueMemberS)
+		if !ok {
+			err = validationError("The provided key element does not match the schema")
+			return
+		}
+
+		if "" == s.Value {
+			err = validationError(fmt.Sprintf("One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: %s", attribute))
+			return
+		}
+
+		parts = append(parts, s.Value)
+	}
+
+	itemKey = strings.Join(parts, "\x00")
+
+	return
+}
+
+// baseKey validates and returns the table key of an item or of a key holding
+// more than the table key attributes.
+func baseKey(item map[string]types.AttributeValue) (itemKey string, err error) {
+	return validateKey(map[string]types.AttributeValue{PartitionKey: item[PartitionKey], SortKey: item[SortKey]})
+}
+
+// validateKeyCondition checks the key condition only constrains the partition
+// key by equality and the sort key by a single supported operator.
+func validateKeyCondition(c condition, partitionKey string, sortKey string) (err error) {
+	var conditions []condition
+
+	if and, ok := c.(andCondition); ok {
+		conditions = []condition{and.left, and.right}
+	} else {
+		conditions = []condition{c}
+	}
+
+	hasPartitionKey := false
+
+	for _, part := range conditions {
+		var attribute string
+
+		switch p := part.(type) {
+		case comparisonCondition:
+			if path, ok := p.left.(pathOperand); ok && 1 == len(path.path) && "<>" != p.comparator {
+				if _, ok = p.right.(valueOperand); ok {
+					attribute = path.path[0].name
+				}
+			}
+
+			if partitionKey == attribute && "=" != p.comparator {
+				attribute = ""
+			}
+		case betweenCondition:
+			if path, ok := p.subject.(pathOperand); ok && 1 == len(path.path) {
+				attribute = path.path[0].name
+			}
+		case functionCondition:
+			if "begins_with" == p.function && 1 == len(p.path) {
+				attribute = p.path[0].name
+			}
+		}
+
+		switch {
+		case partitionKey == attribute && !hasPartitionKey:
+			hasPartitionKey = true
+		case sortKey == attribute && partitionKey != attribute:
+		default:
+			err = validationError("Query key condition not supported")
+			return
+		}
+	}
+
+	if !hasPartitionKey {
+		err = validationError("Query condition missed key schema element: " + partitionKey)
+	}
+
+	return
+}
+
+func parseOptionalCondition(expression *string, attributes *expressionAttributes) (c condition, err error) {
+	if nil == expression {
+		return
+	}
+
+	return parseCondition(*expression, attributes)
+}
+
+func parseOptionalProjection(expression *string, attributes *expressionAttributes) (paths []documentPath, err error) {
+	if nil == expression {
+		return
+	}
+
+	return parseProjection(*expression, attributes)
+}
+
+func checkCondition(c condition, item map[string]types.AttributeValue) (err error) {
+	if nil != c && !c.evaluate(emptyIfNil(item)) {
+		err = &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+	}
+
+	return
+}
+
+func returnValues(returnValue types.ReturnValue, old map[string]types.AttributeValue, updated map[string]types.AttributeValue, actions []updateAction) (attributes map[string]types.AttributeValue) {
+	var source map[string]types.AttributeValue
+
+	switch returnValue {
+	case types.ReturnValueAllOld:
+		return copyItem(old)
+	case types.ReturnValueAllNew:
+		return copyItem(updated)
+	case types.ReturnValueUpdatedOld:
+		source = old
+	case types.ReturnValueUpdatedNew:
+		source = updated
+	default:
+		return
+	}
+
+	attributes = map[string]types.AttributeValue{}
+	for _, a := range actions {
+		if value, ok := source[a.path[0].name]; ok {
+			attributes[a.path[0].name] = copyValue(value)
+		}
+	}
+
+	return
+}
+
+func segmentOf(value types.AttributeValue, totalSegments int32) int32 {
+	h := fnv.New32a()
+
+	if s, ok := value.(*types.AttributeValueMemberS); ok {
+		_, _ = h.Write([]byte(s.Value))
+	}
+
+	return int32(h.Sum32() % uint32(totalSegments))
+}
+
+func emptyIfNil(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	if nil == item {
+		return map[string]types.AttributeValue{}
+	}
+
+	return item
+}
+
+func validationError(message string) error {
+	return &smithy.GenericAPIError{Code: "ValidationException", Message: message}
+}
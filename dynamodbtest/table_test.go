@@ -0,0 +1,231 @@
+package dynamodbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestTable(t *testing.T, count int) *Table {
+	table := New("t")
+
+	for i := 0; i < count; i++ {
+		_, err := table.PutItem(context.TODO(), &dynamodb.PutItemInput{
+			TableName: aws.String("t"),
+			Item: map[string]types.AttributeValue{
+				"PK":     s("P"),
+				"SK":     s(fmt.Sprintf("S#%02d", i)),
+				"GSI1PK": s("G"),
+				"GSI1SK": s(fmt.Sprintf("G#%02d", count-i)),
+				"Count":  n(fmt.Sprint(i)),
+			},
+		})
+		if err != nil {
+			t.Fatalf("PutItem() error = %v", err)
+		}
+	}
+
+	return table
+}
+
+func queryPartition(indexName string, partition string, forward bool, limit int32, exclusiveStartKey map[string]types.AttributeValue) *dynamodb.QueryInput {
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String("t"),
+		KeyConditionExpression:    aws.String(fmt.Sprintf("%sPK = :pk", indexName)),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":pk": s(partition)},
+		ScanIndexForward:          aws.Bool(forward),
+		Limit:                     aws.Int32(limit),
+		ExclusiveStartKey:         exclusiveStartKey,
+	}
+
+	if "" != indexName {
+		input.IndexName = aws.String(indexName)
+	}
+
+	return input
+}
+
+func sortKeys(items []map[string]types.AttributeValue) (keys []string) {
+	for _, item := range items {
+		keys = append(keys, item["SK"].(*types.AttributeValueMemberS).Value)
+	}
+
+	return
+}
+
+func TestQueryPages(t *testing.T) {
+	tests := []struct {
+		name      string
+		indexName string
+		partition string
+		forward   bool
+		want      []string
+	}{
+		{"table forward", "", "P", true, []string{"S#00", "S#01", "S#02", "S#03", "S#04"}},
+		{"table backward", "", "P", false, []string{"S#04", "S#03", "S#02", "S#01", "S#00"}},
+		{"index forward", "GSI1", "G", true, []string{"S#04", "S#03", "S#02", "S#01", "S#00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			var exclusiveStartKey map[string]types.AttributeValue
+
+			table := newTestTable(t, 5)
+
+			for pages := 0; ; pages++ {
+				if pages > 5 {
+					t.Fatalf("Query() does not end")
+				}
+
+				output, err := table.Query(context.TODO(), queryPartition(tt.indexName, tt.partition, tt.forward, 2, exclusiveStartKey))
+				if err != nil {
+					t.Fatalf("Query() error = %v", err)
+				}
+
+				got = append(got, sortKeys(output.Items)...)
+
+				if nil == output.LastEvaluatedKey {
+					break
+				}
+
+				exclusiveStartKey = output.LastEvaluatedKey
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Query() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryResumesAfterDeletedStartKey(t *testing.T) {
+	table := newTestTable(t, 5)
+
+	output, err := table.Query(context.TODO(), queryPartition("", "P", true, 2, nil))
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	_, err = table.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName: aws.String("t"),
+		Key:       map[string]types.AttributeValue{"PK": s("P"), "SK": s("S#01")},
+	})
+	if err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+
+	output, err = table.Query(context.TODO(), queryPartition("", "P", true, 2, output.LastEvaluatedKey))
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+
+	if got, want := sortKeys(output.Items), []string{"S#02", "S#03"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Query() = %v, want %v", got, want)
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(input *dynamodb.QueryInput)
+		want   string
+	}{
+		{"start key of another partition", func(input *dynamodb.QueryInput) {
+			input.ExclusiveStartKey = map[string]types.AttributeValue{"PK": s("Q"), "SK": s("S#00")}
+		}, "outside query boundaries"},
+		{"start key without sort key", func(input *dynamodb.QueryInput) {
+			input.ExclusiveStartKey = map[string]types.AttributeValue{"PK": s("P")}
+		}, "starting key is invalid"},
+		{"unknown index", func(input *dynamodb.QueryInput) {
+			input.IndexName = aws.String("GSI9")
+		}, "does not have the specified index"},
+		{"consistent read on index", func(input *dynamodb.QueryInput) {
+			input.IndexName = aws.String("GSI1")
+			input.KeyConditionExpression = aws.String("GSI1PK = :pk")
+			input.ConsistentRead = aws.Bool(true)
+		}, "Consistent reads are not supported"},
+		{"unused name", func(input *dynamodb.QueryInput) {
+			input.ExpressionAttributeNames = map[string]string{"#u": "Unused"}
+		}, "unused in expressions"},
+		{"key condition on another attribute", func(input *dynamodb.QueryInput) {
+			input.KeyConditionExpression = aws.String("PK = :pk AND Count = :pk")
+		}, "key condition not supported"},
+		{"zero limit", func(input *dynamodb.QueryInput) {
+			input.Limit = aws.Int32(0)
+		}, "Limit must be greater"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := queryPartition("", "P", true, 2, nil)
+			tt.modify(input)
+
+			_, err := newTestTable(t, 2).Query(context.TODO(), input)
+			if nil == err || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Query() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchWriteItemDuplicates(t *testing.T) {
+	table := New("t")
+	put := types.WriteRequest{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{"PK": s("P"), "SK": s("S#00")}}}
+
+	_, err := table.BatchWriteItem(context.TODO(), &dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]types.WriteRequest{"t": {put, put}},
+	})
+	if nil == err || !strings.Contains(err.Error(), "contains duplicates") {
+		t.Errorf("BatchWriteItem() error = %v, want duplicates", err)
+	}
+
+	if 0 != len(table.Items()) {
+		t.Errorf("BatchWriteItem() wrote %d item(s)", len(table.Items()))
+	}
+}
+
+func TestTransactWriteItemsCanceled(t *testing.T) {
+	table := newTestTable(t, 1)
+
+	_, err := table.TransactWriteItems(context.TODO(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName: aws.String("t"),
+				Item:      map[string]types.AttributeValue{"PK": s("P"), "SK": s("S#01")},
+			}},
+			{Update: &types.Update{
+				TableName:                 aws.String("t"),
+				Key:                       map[string]types.AttributeValue{"PK": s("P"), "SK": s("S#00")},
+				UpdateExpression:          aws.String("SET #c = #c + :one"),
+				ConditionExpression:       aws.String("#c > :one"),
+				ExpressionAttributeNames:  map[string]string{"#c": "Count"},
+				ExpressionAttributeValues: map[string]types.AttributeValue{":one": n("1")},
+			}},
+		},
+	})
+
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		t.Fatalf("TransactWriteItems() error = %v, want TransactionCanceledException", err)
+	}
+
+	var codes []string
+	for _, reason := range canceled.CancellationReasons {
+		codes = append(codes, aws.ToString(reason.Code))
+	}
+
+	if want := []string{"None", "ConditionalCheckFailed"}; !reflect.DeepEqual(codes, want) {
+		t.Errorf("CancellationReasons = %v, want %v", codes, want)
+	}
+
+	if 1 != len(table.Items()) {
+		t.Errorf("TransactWriteItems() wrote despite the cancellation: %d item(s)", len(table.Items()))
+	}
+}
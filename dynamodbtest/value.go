@@ -0,0 +1,511 @@
+package dynamodbtest
+
+import (
+	"bytes"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"math/big"
+	"strings"
+)
+
+func resolvePath(item map[string]types.AttributeValue, path documentPath) (value types.AttributeValue, ok bool) {
+	value, ok = item[path[0].name]
+	if !ok {
+		return
+	}
+
+	for _, e := range path[1:] {
+		if e.isIndex {
+			l, isList := value.(*types.AttributeValueMemberL)
+			if !isList || e.index >= len(l.Value) {
+				return nil, false
+			}
+
+			value = l.Value[e.index]
+		} else {
+			m, isMap := value.(*types.AttributeValueMemberM)
+			if !isMap {
+				return nil, false
+			}
+
+			if value, ok = m.Value[e.name]; !ok {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func setPath(item map[string]types.AttributeValue, path documentPath, value types.AttributeValue) (err error) {
+	if 1 == len(path) {
+		item[path[0].name] = value
+		return
+	}
+
+	parent, ok := resolvePath(item, path[:len(path)-1])
+	if !ok {
+		err = validationError("The document path provided in the update expression is invalid for update")
+		return
+	}
+
+	last := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case *types.AttributeValueMemberM:
+		if last.isIndex {
+			err = validationError("The document path provided in the update expression is invalid for update")
+			return
+		}
+
+		p.Value[last.name] = value
+	case *types.AttributeValueMemberL:
+		if !last.isIndex {
+			err = validationError("The document path provided in the update expression is invalid for update")
+			return
+		}
+
+		if last.index < len(p.Value) {
+			p.Value[last.index] = value
+		} else {
+			p.Value = append(p.Value, value)
+		}
+	default:
+		err = validationError("The document path provided in the update expression is invalid for update")
+	}
+
+	return
+}
+
+func removePath(item map[string]types.AttributeValue, path documentPath) {
+	if 1 == len(path) {
+		delete(item, path[0].name)
+		return
+	}
+
+	parent, ok := resolvePath(item, path[:len(path)-1])
+	if !ok {
+		return
+	}
+
+	last := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case *types.AttributeValueMemberM:
+		if !last.isIndex {
+			delete(p.Value, last.name)
+		}
+	case *types.AttributeValueMemberL:
+		if last.isIndex && last.index < len(p.Value) {
+			p.Value = append(p.Value[:last.index], p.Value[last.index+1:]...)
+		}
+	}
+}
+
+// projectItem keeps only the given document paths of item; elements picked
+// out of lists are compacted in path order, as DynamoDB does.
+func projectItem(item map[string]types.AttributeValue, paths []documentPath) map[string]types.AttributeValue {
+	if 0 == len(paths) {
+		return item
+	}
+
+	projected := map[string]types.AttributeValue{}
+
+	for _, path := range paths {
+		value, ok := resolvePath(item, path)
+		if !ok {
+			continue
+		}
+
+		if 1 == len(path) {
+			projected[path[0].name] = copyValue(value)
+			continue
+		}
+
+		var container types.AttributeValue = &types.AttributeValueMemberM{Value: projected}
+
+		for i, e := range path {
+			last := i == len(path)-1
+			var next types.AttributeValue
+
+			if !last {
+				if path[i+1].isIndex {
+					next = &types.AttributeValueMemberL{}
+				} else {
+					next = &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}}
+				}
+			} else {
+				next = copyValue(value)
+			}
+
+			switch c := container.(type) {
+			case *types.AttributeValueMemberM:
+				if existing, ok := c.Value[e.name]; ok && !last {
+					next = existing
+				} else {
+					c.Value[e.name] = next
+				}
+			case *types.AttributeValueMemberL:
+				c.Value = append(c.Value, next)
+			}
+
+			container = next
+		}
+	}
+
+	return projected
+}
+
+func copyItem(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	if nil == item {
+		return nil
+	}
+
+	copied := make(map[string]types.AttributeValue, len(item))
+	for k, v := range item {
+		copied[k] = copyValue(v)
+	}
+
+	return copied
+}
+
+func copyValue(value types.AttributeValue) types.AttributeValue {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		return &types.AttributeValueMemberS{Value: v.Value}
+	case *types.AttributeValueMemberN:
+		return &types.AttributeValueMemberN{Value: v.Value}
+	case *types.AttributeValueMemberB:
+		return &types.AttributeValueMemberB{Value: append([]byte(nil), v.Value...)}
+	case *types.AttributeValueMemberBOOL:
+		return &types.AttributeValueMemberBOOL{Value: v.Value}
+	case *types.AttributeValueMemberNULL:
+		return &types.AttributeValueMemberNULL{Value: v.Value}
+	case *types.AttributeValueMemberSS:
+		return &types.AttributeValueMemberSS{Value: append([]string(nil), v.Value...)}
+	case *types.AttributeValueMemberNS:
+		return &types.AttributeValueMemberNS{Value: append([]string(nil), v.Value...)}
+	case *types.AttributeValueMemberBS:
+		bs := make([][]byte, len(v.Value))
+		for i, b := range v.Value {
+			bs[i] = append([]byte(nil), b...)
+		}
+		return &types.AttributeValueMemberBS{Value: bs}
+	case *types.AttributeValueMemberL:
+		l := make([]types.AttributeValue, len(v.Value))
+		for i, e := range v.Value {
+			l[i] = copyValue(e)
+		}
+		return &types.AttributeValueMemberL{Value: l}
+	case *types.AttributeValueMemberM:
+		return &types.AttributeValueMemberM{Value: copyItem(v.Value)}
+	}
+
+	return value
+}
+
+func attributeType(value types.AttributeValue) string {
+	switch value.(type) {
+	case *types.AttributeValueMemberS:
+		return "S"
+	case *types.AttributeValueMemberN:
+		return "N"
+	case *types.AttributeValueMemberB:
+		return "B"
+	case *types.AttributeValueMemberBOOL:
+		return "BOOL"
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	case *types.AttributeValueMemberSS:
+		return "SS"
+	case *types.AttributeValueMemberNS:
+		return "NS"
+	case *types.AttributeValueMemberBS:
+		return "BS"
+	case *types.AttributeValueMemberL:
+		return "L"
+	case *types.AttributeValueMemberM:
+		return "M"
+	}
+
+	return ""
+}
+
+func isAttributeType(name string) bool {
+	switch name {
+	case "S", "N", "B", "BOOL", "NULL", "SS", "NS", "BS", "L", "M":
+		return true
+	}
+
+	return false
+}
+
+func attributeSize(value types.AttributeValue) (size int, ok bool) {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberB:
+		return len(v.Value), true
+	case *types.AttributeValueMemberSS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberNS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberBS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberL:
+		return len(v.Value), true
+	case *types.AttributeValueMemberM:
+		return len(v.Value), true
+	}
+
+	return
+}
+
+func parseNumber(s string) (r *big.Rat, ok bool) {
+	return new(big.Rat).SetString(s)
+}
+
+func formatNumber(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+
+	s := r.FloatString(38)
+	s = strings.TrimRight(s, "0")
+
+	return strings.TrimSuffix(s, ".")
+}
+
+func addNumbers(left, right types.AttributeValue, subtract bool) (value types.AttributeValue, err error) {
+	l, lok := left.(*types.AttributeValueMemberN)
+	r, rok := right.(*types.AttributeValueMemberN)
+	if !lok || !rok {
+		err = validationError("An operand in the update expression has an incorrect data type")
+		return
+	}
+
+	a, aok := parseNumber(l.Value)
+	b, bok := parseNumber(r.Value)
+	if !aok || !bok {
+		err = validationError("An operand in the update expression has an invalid number")
+		return
+	}
+
+	if subtract {
+		value = &types.AttributeValueMemberN{Value: formatNumber(new(big.Rat).Sub(a, b))}
+	} else {
+		value = &types.AttributeValueMemberN{Value: formatNumber(new(big.Rat).Add(a, b))}
+	}
+
+	return
+}
+
+// compareValues orders two scalars of the same type; ok is false when the
+// values are not comparable.
+func compareValues(left, right types.AttributeValue) (cmp int, ok bool) {
+	switch l := left.(type) {
+	case *types.AttributeValueMemberS:
+		if r, isS := right.(*types.AttributeValueMemberS); isS {
+			return strings.Compare(l.Value, r.Value), true
+		}
+	case *types.AttributeValueMemberN:
+		if r, isN := right.(*types.AttributeValueMemberN); isN {
+			a, aok := parseNumber(l.Value)
+			b, bok := parseNumber(r.Value)
+			if aok && bok {
+				return a.Cmp(b), true
+			}
+		}
+	case *types.AttributeValueMemberB:
+		if r, isB := right.(*types.AttributeValueMemberB); isB {
+			return bytes.Compare(l.Value, r.Value), true
+		}
+	}
+
+	return
+}
+
+func equalValues(left, right types.AttributeValue) bool {
+	if attributeType(left) != attributeType(right) {
+		return false
+	}
+
+	switch l := left.(type) {
+	case *types.AttributeValueMemberS, *types.AttributeValueMemberN, *types.AttributeValueMemberB:
+		cmp, ok := compareValues(left, right)
+		return ok && 0 == cmp
+	case *types.AttributeValueMemberBOOL:
+		return l.Value == right.(*types.AttributeValueMemberBOOL).Value
+	case *types.AttributeValueMemberNULL:
+		return true
+	case *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+		le, re := setElements(left), setElements(right)
+		if len(le) != len(re) {
+			return false
+		}
+
+		for _, e := range le {
+			if !containsElement(re, e) {
+				return false
+			}
+		}
+
+		return true
+	case *types.AttributeValueMemberL:
+		r := right.(*types.AttributeValueMemberL)
+		if len(l.Value) != len(r.Value) {
+			return false
+		}
+
+		for i := range l.Value {
+			if !equalValues(l.Value[i], r.Value[i]) {
+				return false
+			}
+		}
+
+		return true
+	case *types.AttributeValueMemberM:
+		r := right.(*types.AttributeValueMemberM)
+		if len(l.Value) != len(r.Value) {
+			return false
+		}
+
+		for k, v := range l.Value {
+			rv, ok := r.Value[k]
+			if !ok || !equalValues(v, rv) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// setElements returns the members of a set as scalar attribute values.
+func setElements(value types.AttributeValue) (elements []types.AttributeValue) {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberSS:
+		for _, e := range v.Value {
+			elements = append(elements, &types.AttributeValueMemberS{Value: e})
+		}
+	case *types.AttributeValueMemberNS:
+		for _, e := range v.Value {
+			elements = append(elements, &types.AttributeValueMemberN{Value: e})
+		}
+	case *types.AttributeValueMemberBS:
+		for _, e := range v.Value {
+			elements = append(elements, &types.AttributeValueMemberB{Value: e})
+		}
+	}
+
+	return
+}
+
+func containsElement(elements []types.AttributeValue, element types.AttributeValue) bool {
+	for _, e := range elements {
+		if equalValues(e, element) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsValue(value, operand types.AttributeValue) bool {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		o, ok := operand.(*types.AttributeValueMemberS)
+		return ok && strings.Contains(v.Value, o.Value)
+	case *types.AttributeValueMemberB:
+		o, ok := operand.(*types.AttributeValueMemberB)
+		return ok && bytes.Contains(v.Value, o.Value)
+	case *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+		return containsElement(setElements(value), operand)
+	case *types.AttributeValueMemberL:
+		return containsElement(v.Value, operand)
+	}
+
+	return false
+}
+
+// addToSet implements the ADD update action for numbers and sets.
+func addToSet(current types.AttributeValue, exists bool, value types.AttributeValue) (result types.AttributeValue, err error) {
+	if !exists {
+		return copyValue(value), nil
+	}
+
+	if _, ok := value.(*types.AttributeValueMemberN); ok {
+		return addNumbers(current, value, false)
+	}
+
+	if "" == setType(value) || attributeType(current) != attributeType(value) {
+		err = validationError("An operand in the update expression has an incorrect data type")
+		return
+	}
+
+	elements := setElements(current)
+	for _, e := range setElements(value) {
+		if !containsElement(elements, e) {
+			elements = append(elements, e)
+		}
+	}
+
+	return buildSet(setType(value), elements), nil
+}
+
+// deleteFromSet implements the DELETE update action; ok is false when the
+// resulting set is empty and the attribute should be removed.
+func deleteFromSet(current types.AttributeValue, value types.AttributeValue) (result types.AttributeValue, ok bool, err error) {
+	if "" == setType(value) || attributeType(current) != attributeType(value) {
+		err = validationError("An operand in the update expression has an incorrect data type")
+		return
+	}
+
+	var elements []types.AttributeValue
+
+	remove := setElements(value)
+	for _, e := range setElements(current) {
+		if !containsElement(remove, e) {
+			elements = append(elements, e)
+		}
+	}
+
+	if 0 == len(elements) {
+		return
+	}
+
+	return buildSet(setType(value), elements), true, nil
+}
+
+func setType(value types.AttributeValue) string {
+	switch t := attributeType(value); t {
+	case "SS", "NS", "BS":
+		return t
+	}
+
+	return ""
+}
+
+func buildSet(t string, elements []types.AttributeValue) types.AttributeValue {
+	switch t {
+	case "SS":
+		s := make([]string, len(elements))
+		for i, e := range elements {
+			s[i] = e.(*types.AttributeValueMemberS).Value
+		}
+		return &types.AttributeValueMemberSS{Value: s}
+	case "NS":
+		s := make([]string, len(elements))
+		for i, e := range elements {
+			s[i] = e.(*types.AttributeValueMemberN).Value
+		}
+		return &types.AttributeValueMemberNS{Value: s}
+	default:
+		s := make([][]byte, len(elements))
+		for i, e := range elements {
+			s[i] = e.(*types.AttributeValueMemberB).Value
+		}
+		return &types.AttributeValueMemberBS{Value: s}
+	}
+}
@@ -0,0 +1,248 @@
+package dynamodb_client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"gitlab.com/ptami_lib/util"
+)
+
+var (
+	ErrNotFound               = errors.New("item not found")
+	ErrUnsupportedSortKeyType = errors.New("not supported sort key type")
+	ErrUnsupportedFunction    = errors.New("unsupported function")
+	ErrConditionFailed        = errors.New("condition failed")
+	ErrInvalidFilter          = errors.New("invalid filter")
+	ErrUnprocessedItem        = errors.New("item left unprocessed after retries")
+	ErrDuplicateItem          = errors.New("item key repeated in the batch")
+	ErrTransactionCanceled    = errors.New("transaction canceled")
+	ErrVersionConflict        = errors.New("version conflict")
+	ErrInvalidOrder           = errors.New("invalid order")
+	ErrInvalidCursor          = errors.New("invalid cursor")
+	ErrInvalidPath            = errors.New("invalid path")
+	ErrConsistentReadOnIndex  = errors.New("consistent read is not supported on global secondary indexes")
+	ErrInvalidKey             = errors.New("invalid key")
+	ErrInvalidTransactionSize = errors.New("invalid transaction size")
+	ErrVersionedBatchItem     = errors.New("versioned items cannot be batch written")
+)
+
+type NotFoundError struct {
+	Key Key
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("item not found (%s)", util.StructToString(e.Key))
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return ErrNotFound == target
+}
+
+type UnsupportedSortKeyTypeError struct {
+	Key         Key
+	SortKeyType string
+}
+
+func (e *UnsupportedSortKeyTypeError) Error() string {
+	return fmt.Sprintf("not supported sort key type (%s)", e.SortKeyType)
+}
+
+func (e *UnsupportedSortKeyTypeError) Is(target error) bool {
+	return ErrUnsupportedSortKeyType == target
+}
+
+// UnsupportedFunctionError reports a "Fn:function_name:key" property of
+// UpdateItem whose function name or format is not supported.
+type UnsupportedFunctionError struct {
+	Function string
+}
+
+func (e *UnsupportedFunctionError) Error() string {
+	return fmt.Sprintf("unsupported function (%s)", e.Function)
+}
+
+func (e *UnsupportedFunctionError) Is(target error) bool {
+	return ErrUnsupportedFunction == target
+}
+
+// ConditionFailedError wraps the ConditionalCheckFailedException returned for
+// a write on Key.
+type ConditionFailedError struct {
+	Key Key
+	Err error
+}
+
+func (e *ConditionFailedError) Error() string {
+	return fmt.Sprintf("condition failed (%s)", util.StructToString(e.Key))
+}
+
+func (e *ConditionFailedError) Is(target error) bool {
+	return ErrConditionFailed == target
+}
+
+func (e *ConditionFailedError) Unwrap() error {
+	return e.Err
+}
+
+// VersionConflictError reports a versioned write on Key whose expected
+// Version no longer matches the stored one.
+type VersionConflictError struct {
+	Key     Key
+	Version uint64
+	Err     error
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict, expected version %d (%s)", e.Version, util.StructToString(e.Key))
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return ErrVersionConflict == target
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidFilterError reports a malformed filter or condition; Err holds the
+// underlying error, such as the *InvalidPathError of its field, when any.
+type InvalidFilterError struct {
+	Field  string
+	Reason string
+	Err    error
+}
+
+func (e *InvalidFilterError) Error() string {
+	return fmt.Sprintf("invalid filter (%s): %s", e.Field, e.Reason)
+}
+
+func (e *InvalidFilterError) Is(target error) bool {
+	return ErrInvalidFilter == target
+}
+
+func (e *InvalidFilterError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidOrderError reports a QueryOption.Order that cannot be honoured, such
+// as a client side sort combined with paging.
+type InvalidOrderError struct {
+	Field  string
+	Reason string
+}
+
+func (e *InvalidOrderError) Error() string {
+	return fmt.Sprintf("invalid order (%s): %s", e.Field, e.Reason)
+}
+
+func (e *InvalidOrderError) Is(target error) bool {
+	return ErrInvalidOrder == target
+}
+
+type InvalidCursorError struct {
+	Reason string
+}
+
+func (e *InvalidCursorError) Error() string {
+	return fmt.Sprintf("invalid cursor: %s", e.Reason)
+}
+
+func (e *InvalidCursorError) Is(target error) bool {
+	return ErrInvalidCursor == target
+}
+
+// InvalidPathError reports a malformed document path of a filter, projection
+// or update, such as "a..b" or "a[x]".
+type InvalidPathError struct {
+	Path   string
+	Reason string
+}
+
+func (e *InvalidPathError) Error() string {
+	return fmt.Sprintf("invalid path (%s): %s", e.Path, e.Reason)
+}
+
+func (e *InvalidPathError) Is(target error) bool {
+	return ErrInvalidPath == target
+}
+
+// ConsistentReadOnIndexError reports a WithConsistentRead read of a global
+// secondary index, which DynamoDB only reads eventually consistently.
+type ConsistentReadOnIndexError struct {
+	Key Key
+}
+
+func (e *ConsistentReadOnIndexError) Error() string {
+	return fmt.Sprintf("%s (%s)", ErrConsistentReadOnIndex, util.StructToString(e.Key))
+}
+
+func (e *ConsistentReadOnIndexError) Is(target error) bool {
+	return ErrConsistentReadOnIndex == target
+}
+
+// InvalidKeyError reports a key that cannot address an item, such as a key
+// without SK or a key of an index given to a batch or transactional operation.
+type InvalidKeyError struct {
+	Key    Key
+	Reason string
+}
+
+func (e *InvalidKeyError) Error() string {
+	return fmt.Sprintf("invalid key (%s): %s", util.StructToString(e.Key), e.Reason)
+}
+
+func (e *InvalidKeyError) Is(target error) bool {
+	return ErrInvalidKey == target
+}
+
+// InvalidTransactionSizeError reports a transaction without operations or
+// with more operations than TransactWriteItems and TransactGetItems accept.
+type InvalidTransactionSizeError struct {
+	Operations int
+}
+
+func (e *InvalidTransactionSizeError) Error() string {
+	return fmt.Sprintf("a transaction must contain between 1 and %d operations (%d)", maxTransactionItems, e.Operations)
+}
+
+func (e *InvalidTransactionSizeError) Is(target error) bool {
+	return ErrInvalidTransactionSize == target
+}
+
+// VersionedBatchItemError reports an item with a Version given to
+// BatchInsert, which cannot check the stored Version of the item it
+// overwrites.
+type VersionedBatchItemError struct {
+	Key     Key
+	Version uint64
+}
+
+func (e *VersionedBatchItemError) Error() string {
+	return fmt.Sprintf("versioned items cannot be batch written, expected version %d (%s)", e.Version, util.StructToString(e.Key))
+}
+
+func (e *VersionedBatchItemError) Is(target error) bool {
+	return ErrVersionedBatchItem == target
+}
+
+func wrapConditionFailed(key Key, err error) error {
+	var conditionalCheckFailed *types.ConditionalCheckFailedException
+
+	if errors.As(err, &conditionalCheckFailed) {
+		return &ConditionFailedError{Key: key, Err: err}
+	}
+
+	return err
+}
+
+func keyOfItem(item map[string]types.AttributeValue) (key Key) {
+	if pk, ok := item["PK"].(*types.AttributeValueMemberS); ok {
+		key.PK = &pk.Value
+	}
+
+	if sk, ok := item["SK"].(*types.AttributeValueMemberS); ok {
+		key.SK = &sk.Value
+	}
+
+	return
+}
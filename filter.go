@@ -0,0 +1,588 @@
+package dynamodb_client
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Filter types of QueryOption.Filter. Every filter is a map with a "field", a
+// "type" and, unless the type tests existence, a "keyword"; "size" filters
+// also take an "operator".
+const (
+	FilterTypeKeyword        = "keyword"        // contains(field, keyword)
+	FilterTypeNotContains    = "not_contains"   // NOT contains(field, keyword)
+	FilterTypeConst          = "const"          // field = keyword
+	FilterTypeNotEqual       = "not_equal"      // field <> keyword
+	FilterTypeLessThan       = "lt"             // field < keyword
+	FilterTypeLessOrEqual    = "lte"            // field <= keyword
+	FilterTypeGreaterThan    = "gt"             // field > keyword
+	FilterTypeGreaterOrEqual = "gte"            // field >= keyword
+	FilterTypeDate           = "date"           // field BETWEEN start AND end, keyword being "start/end"
+	FilterTypeBetween        = "between"        // field BETWEEN keyword[0] AND keyword[1]
+	FilterTypeBeginsWith     = "begins_with"    // begins_with(field, keyword)
+	FilterTypeIn             = "in"             // field IN (keyword...)
+	FilterTypeExist          = "exist"          // attribute_exists(field)
+	FilterTypeNotExist       = "not_exist"      // attribute_not_exists(field)
+	FilterTypeAttributeType  = "attribute_type" // attribute_type(field, keyword)
+	FilterTypeSize           = "size"           // size(field) operator keyword
+)
+
+// Filter groups of QueryOption.Filter.
+const (
+	FilterGroupAnd = "and"
+	FilterGroupOr  = "or"
+	FilterGroupNot = "not"
+)
+
+// maxFilterInValues is the number of operands DynamoDB accepts for IN.
+const maxFilterInValues = 100
+
+var filterComparators = map[string]string{
+	FilterTypeConst:          "=",
+	FilterTypeNotEqual:       "<>",
+	FilterTypeLessThan:       "<",
+	FilterTypeLessOrEqual:    "<=",
+	FilterTypeGreaterThan:    ">",
+	FilterTypeGreaterOrEqual: ">=",
+}
+
+var filterAttributeTypes = []string{"S", "SS", "N", "NS", "B", "BS", "BOOL", "NULL", "L", "M"}
+
+// Condition is a typed filter of QueryOption or condition of a write, built
+// with Eq, Contains, Between, Exists, And, Or, Not and the other functions of
+// this file.
+type Condition interface {
+	build(a *placeholderAllocator) (string, error)
+}
+
+type filterCondition struct {
+	field      string
+	filterType string
+	operator   string
+	keyword    interface{}
+}
+
+type groupCondition struct {
+	operator   string
+	conditions []Condition
+}
+
+type notCondition struct {
+	condition Condition
+}
+
+// Eq matches items whose field equals value.
+func Eq(field string, value interface{}) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeConst, keyword: value}
+}
+
+// Ne matches items whose field differs from value.
+func Ne(field string, value interface{}) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeNotEqual, keyword: value}
+}
+
+// Lt matches items whose field is less than value.
+func Lt(field string, value interface{}) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeLessThan, keyword: value}
+}
+
+// Lte matches items whose field is less than or equal to value.
+func Lte(field string, value interface{}) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeLessOrEqual, keyword: value}
+}
+
+// Gt matches items whose field is greater than value.
+func Gt(field string, value interface{}) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeGreaterThan, keyword: value}
+}
+
+// Gte matches items whose field is greater than or equal to value.
+func Gte(field string, value interface{}) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeGreaterOrEqual, keyword: value}
+}
+
+// Between matches items whose field lies between start and end, inclusive.
+func Between(field string, start interface{}, end interface{}) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeBetween, keyword: []interface{}{start, end}}
+}
+
+// BeginsWith matches items whose string field starts with prefix.
+func BeginsWith(field string, prefix string) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeBeginsWith, keyword: prefix}
+}
+
+// Contains matches items whose string field contains value, or whose set or
+// list field holds value.
+func Contains(field string, value interface{}) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeKeyword, keyword: value}
+}
+
+// NotContains is the negation of Contains.
+func NotContains(field string, value interface{}) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeNotContains, keyword: value}
+}
+
+// In matches items whose field equals one of values.
+func In(field string, values ...interface{}) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeIn, keyword: values}
+}
+
+// Exists matches items having field.
+func Exists(field string) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeExist}
+}
+
+// NotExists matches items without field.
+func NotExists(field string) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeNotExist}
+}
+
+// AttributeType matches items whose field has the DynamoDB type attributeType,
+// such as "S", "N" or "M".
+func AttributeType(field string, attributeType string) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeAttributeType, keyword: attributeType}
+}
+
+// Size matches items whose field size compares to size with operator, one of
+// =, <>, <, <=, > and >=.
+func Size(field string, operator string, size int) Condition {
+	return &filterCondition{field: field, filterType: FilterTypeSize, operator: operator, keyword: size}
+}
+
+// And matches items matching every condition.
+func And(conditions ...Condition) Condition {
+	return &groupCondition{operator: FilterGroupAnd, conditions: conditions}
+}
+
+// Or matches items matching any condition.
+func Or(conditions ...Condition) Condition {
+	return &groupCondition{operator: FilterGroupOr, conditions: conditions}
+}
+
+// Not matches items not matching condition.
+func Not(condition Condition) Condition {
+	return &notCondition{condition: condition}
+}
+
+// ConditionFromFilter converts a filter in the map shape of QueryOption.Filter
+// to a Condition, returning an error matching ErrInvalidFilter when the map is
+// malformed. The entries of filter are joined with AND in the order of their
+// keys. An entry is either a filter or a group holding a single "and" or "or"
+// key, whose value lists entries (as a list, or a map in key order), or a
+// single "not" key negating one entry:
+//
+//	{"status": {"or": [{"field": "status", "type": "const", "keyword": "A"},
+//	                   {"field": "status", "type": "const", "keyword": "B"}]},
+//	 "deleted": {"not": {"field": "deletedAt", "type": "exist"}}}
+func ConditionFromFilter(filter map[string]interface{}) (condition Condition, err error) {
+	if 0 == len(filter) {
+		return
+	}
+
+	entries, _ := filterEntries(filter)
+
+	return conditionFromFilterGroup(FilterGroupAnd, entries)
+}
+
+// filterEntries lists the entries of a group, maps being read in key order.
+func filterEntries(entries interface{}) (list []interface{}, ok bool) {
+	switch v := entries.(type) {
+	case []interface{}:
+		return v, true
+	case []map[string]interface{}:
+		list = make([]interface{}, len(v))
+		for i, entry := range v {
+			list[i] = entry
+		}
+		return list, true
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		list = make([]interface{}, len(keys))
+		for i, k := range keys {
+			list[i] = v[k]
+		}
+		return list, true
+	}
+
+	return nil, false
+}
+
+func conditionFromFilterGroup(operator string, entries []interface{}) (condition Condition, err error) {
+	if 0 == len(entries) {
+		err = &InvalidFilterError{Reason: fmt.Sprintf("%s group must list at least one entry", operator)}
+		return
+	}
+
+	conditions := make([]Condition, len(entries))
+	for i, entry := range entries {
+		conditions[i], err = conditionFromFilterEntry(entry)
+		if err != nil {
+			return
+		}
+	}
+
+	condition = &groupCondition{operator: operator, conditions: conditions}
+
+	return
+}
+
+func conditionFromFilterEntry(entry interface{}) (condition Condition, err error) {
+	item, ok := entry.(map[string]interface{})
+	if !ok {
+		err = &InvalidFilterError{Reason: fmt.Sprintf("unsupported filter entry (%T)", entry)}
+		return
+	}
+
+	if _, isFilter := item["field"]; isFilter {
+		return conditionFromFilterItem(item)
+	}
+
+	if 1 != len(item) {
+		err = &InvalidFilterError{Reason: "a filter group must hold exactly one of and, or, not"}
+		return
+	}
+
+	for operator, value := range item {
+		switch operator {
+		case FilterGroupAnd, FilterGroupOr:
+			entries, ok := filterEntries(value)
+			if !ok {
+				err = &InvalidFilterError{Reason: fmt.Sprintf("%s group must list its entries", operator)}
+				return
+			}
+
+			condition, err = conditionFromFilterGroup(operator, entries)
+		case FilterGroupNot:
+			condition, err = conditionFromFilterEntry(value)
+			if err != nil {
+				return
+			}
+
+			condition = &notCondition{condition: condition}
+		default:
+			err = &InvalidFilterError{Reason: fmt.Sprintf("unsupported filter group (%s)", operator)}
+		}
+	}
+
+	return
+}
+
+func conditionFromFilterItem(item map[string]interface{}) (condition Condition, err error) {
+	field, ok := item["field"].(string)
+	if !ok || "" == field {
+		err = &InvalidFilterError{Reason: "field must be a non-empty string"}
+		return
+	}
+
+	filterType, ok := item["type"].(string)
+	if !ok {
+		err = &InvalidFilterError{Field: field, Reason: "type must be a string"}
+		return
+	}
+
+	operator, ok := item["operator"].(string)
+	if !ok && nil != item["operator"] {
+		err = &InvalidFilterError{Field: field, Reason: "operator must be a string"}
+		return
+	}
+
+	condition = &filterCondition{field: field, filterType: filterType, operator: operator, keyword: item["keyword"]}
+
+	return
+}
+
+// buildConditionExpression compiles condition, adding its names and values to
+// expressionAttributeNames and expressionAttributeValues.
+func buildConditionExpression(condition Condition, expressionAttributeValues map[string]types.AttributeValue, expressionAttributeNames map[string]string) (expression string, err error) {
+	if nil == condition {
+		return
+	}
+
+	a := newPlaceholderAllocator(expressionAttributeNames, expressionAttributeValues)
+
+	// the outermost group needs no parentheses
+	if group, ok := condition.(*groupCondition); ok {
+		return group.join(a)
+	}
+
+	return condition.build(a)
+}
+
+func processQueryOptionFilter(filter map[string]interface{}, expressionAttributeValues map[string]types.AttributeValue, expressionAttributeName map[string]string) (filterExpression string, err error) {
+	condition, err := ConditionFromFilter(filter)
+	if err != nil {
+		return
+	}
+
+	return buildConditionExpression(condition, expressionAttributeValues, expressionAttributeName)
+}
+
+// processQueryOptionCondition compiles the Filter and Condition of
+// queryOption, joined with AND when both are set.
+func processQueryOptionCondition(queryOption QueryOption, expressionAttributeValues map[string]types.AttributeValue, expressionAttributeName map[string]string) (filterExpression string, err error) {
+	condition, err := ConditionFromFilter(queryOption.Filter)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case nil == condition:
+		condition = queryOption.Condition
+	case nil != queryOption.Condition:
+		condition = And(condition, queryOption.Condition)
+	}
+
+	return buildConditionExpression(condition, expressionAttributeValues, expressionAttributeName)
+}
+
+func (c *groupCondition) build(a *placeholderAllocator) (expression string, err error) {
+	expression, err = c.join(a)
+	expression = fmt.Sprintf("(%s)", expression)
+
+	return
+}
+
+func (c *groupCondition) join(a *placeholderAllocator) (expression string, err error) {
+	if 0 == len(c.conditions) {
+		err = &InvalidFilterError{Reason: fmt.Sprintf("%s group must list at least one entry", c.operator)}
+		return
+	}
+
+	expressions := make([]string, len(c.conditions))
+
+	for i, condition := range c.conditions {
+		if nil == condition {
+			err = &InvalidFilterError{Reason: fmt.Sprintf("%s group holds a nil condition", c.operator)}
+			return
+		}
+
+		expressions[i], err = condition.build(a)
+		if err != nil {
+			return
+		}
+	}
+
+	expression = strings.Join(expressions, fmt.Sprintf(" %s ", strings.ToUpper(c.operator)))
+
+	return
+}
+
+func (c *notCondition) build(a *placeholderAllocator) (expression string, err error) {
+	if nil == c.condition {
+		err = &InvalidFilterError{Reason: "not holds a nil condition"}
+		return
+	}
+
+	expression, err = c.condition.build(a)
+	expression = fmt.Sprintf("NOT (%s)", expression)
+
+	return
+}
+
+func (c *filterCondition) build(a *placeholderAllocator) (expression string, err error) {
+	return getWhere(c, a)
+}
+
+// getWhere returns the condition of a single filter, allocating the
+// placeholders of its path and values from a.
+func getWhere(c *filterCondition, a *placeholderAllocator) (filterExpression string, err error) {
+	var av types.AttributeValue
+	var values []types.AttributeValue
+
+	searchType := c.filterType
+	keyword := c.keyword
+
+	invalid := func(reason string, args ...interface{}) error {
+		return &InvalidFilterError{Field: c.field, Reason: fmt.Sprintf(reason, args...)}
+	}
+
+	// validate and marshal the keyword before allocating any placeholder
+	switch searchType {
+	case FilterTypeExist, FilterTypeNotExist:
+	case FilterTypeDate:
+		keywordString, _ := keyword.(string)
+		date := strings.Split(keywordString, "/")
+		if 2 != len(date) {
+			err = invalid("date keyword must be formatted as start/end")
+			return
+		}
+
+		values = []types.AttributeValue{&types.AttributeValueMemberS{Value: date[0]}, &types.AttributeValueMemberS{Value: date[1]}}
+	case FilterTypeBetween:
+		values, err = marshalFilterKeywordList(keyword)
+		if err != nil || 2 != len(values) {
+			err = invalid("between keyword must be a list of two values")
+			return
+		}
+	case FilterTypeIn:
+		values, err = marshalFilterKeywordList(keyword)
+		if err != nil || 0 == len(values) || len(values) > maxFilterInValues {
+			err = invalid("in keyword must be a list of 1 to %d values", maxFilterInValues)
+			return
+		}
+	case FilterTypeAttributeType:
+		attributeType, _ := keyword.(string)
+		if !containsString(filterAttributeTypes, attributeType) {
+			err = invalid("attribute_type keyword must be one of %s", strings.Join(filterAttributeTypes, ", "))
+			return
+		}
+
+		av = &types.AttributeValueMemberS{Value: attributeType}
+	case FilterTypeSize:
+		if "" != c.operator && !containsString([]string{"=", "<>", "<", "<=", ">", ">="}, c.operator) {
+			err = invalid("unsupported size operator (%s)", c.operator)
+			return
+		}
+
+		av, err = marshalFilterKeyword(c.field, keyword)
+		if err != nil {
+			return
+		}
+
+		if _, ok := av.(*types.AttributeValueMemberN); !ok {
+			err = invalid("size keyword must be a number")
+			return
+		}
+	case FilterTypeKeyword, FilterTypeNotContains, FilterTypeBeginsWith:
+		av, err = marshalFilterKeyword(c.field, keyword)
+	default:
+		if _, ok := filterComparators[searchType]; !ok {
+			err = invalid("unsupported filter type (%s)", searchType)
+			return
+		}
+
+		av, err = marshalFilterKeyword(c.field, keyword)
+	}
+
+	if err != nil {
+		return
+	}
+
+	// a list keyword is a string set, which DynamoDB only compares for equality
+	if FilterTypeConst != searchType && FilterTypeNotEqual != searchType {
+		for _, v := range append([]types.AttributeValue{av}, values...) {
+			if _, ok := v.(*types.AttributeValueMemberSS); ok {
+				err = invalid("list keyword is only supported by the %s and %s filter types", FilterTypeConst, FilterTypeNotEqual)
+				return
+			}
+		}
+	}
+
+	path, err := a.path(c.field)
+	if err != nil {
+		err = &InvalidFilterError{Field: c.field, Reason: err.Error(), Err: err}
+		return
+	}
+
+	var value string
+	if nil != av {
+		value = a.value(av)
+	}
+
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		placeholders[i] = a.value(v)
+	}
+
+	switch searchType {
+	case FilterTypeExist:
+		filterExpression = fmt.Sprintf("attribute_exists(%s)", path)
+	case FilterTypeNotExist:
+		filterExpression = fmt.Sprintf("attribute_not_exists(%s)", path)
+	case FilterTypeDate, FilterTypeBetween:
+		filterExpression = fmt.Sprintf("%s BETWEEN %s AND %s", path, placeholders[0], placeholders[1])
+	case FilterTypeIn:
+		filterExpression = fmt.Sprintf("%s IN (%s)", path, strings.Join(placeholders, ", "))
+	case FilterTypeAttributeType:
+		filterExpression = fmt.Sprintf("attribute_type(%s, %s)", path, value)
+	case FilterTypeSize:
+		operator := c.operator
+		if "" == operator {
+			operator = "="
+		}
+
+		filterExpression = fmt.Sprintf("size(%s) %s %s", path, operator, value)
+	case FilterTypeKeyword:
+		filterExpression = fmt.Sprintf("contains (%s, %s)", path, value)
+	case FilterTypeNotContains:
+		filterExpression = fmt.Sprintf("NOT contains (%s, %s)", path, value)
+	case FilterTypeBeginsWith:
+		filterExpression = fmt.Sprintf("begins_with(%s, %s)", path, value)
+	default:
+		filterExpression = fmt.Sprintf("%s %s %s", path, filterComparators[searchType], value)
+	}
+
+	return
+}
+
+// marshalFilterKeyword converts a filter keyword to its attribute value:
+// numbers to N, bools to BOOL, strings to S, lists of strings to SS and nil
+// to NULL. getWhere only accepts SS for the const and not_equal filter types.
+func marshalFilterKeyword(field string, keyword interface{}) (av types.AttributeValue, err error) {
+	switch v := keyword.(type) {
+	case nil:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case bool:
+		return &types.AttributeValueMemberBOOL{Value: v}, nil
+	case string:
+		return &types.AttributeValueMemberS{Value: v}, nil
+	case json.Number:
+		return &types.AttributeValueMemberN{Value: v.String()}, nil
+	case []string:
+		if 0 == len(v) {
+			break
+		}
+
+		return &types.AttributeValueMemberSS{Value: v}, nil
+	case []interface{}:
+		strs := make([]string, len(v))
+		for i, s := range v {
+			str, ok := s.(string)
+			if !ok {
+				return nil, &InvalidFilterError{Field: field, Reason: "list keyword must only hold strings"}
+			}
+			strs[i] = str
+		}
+
+		return marshalFilterKeyword(field, strs)
+	}
+
+	switch reflect.ValueOf(keyword).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return attributevalue.Marshal(keyword)
+	}
+
+	return nil, &InvalidFilterError{Field: field, Reason: fmt.Sprintf("unsupported keyword type (%T)", keyword)}
+}
+
+// marshalFilterKeywordList converts the list keyword of a between or in
+// filter, marshalling each of its values like marshalFilterKeyword.
+func marshalFilterKeywordList(keyword interface{}) (avs []types.AttributeValue, err error) {
+	list := reflect.ValueOf(keyword)
+	if reflect.Slice != list.Kind() && reflect.Array != list.Kind() {
+		err = &InvalidFilterError{Reason: "keyword must be a list"}
+		return
+	}
+
+	avs = make([]types.AttributeValue, list.Len())
+	for i := range avs {
+		avs[i], err = marshalFilterKeyword("", list.Index(i).Interface())
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,58 @@
+package dynamodb_client_test
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dc "gitlab.com/ptami_lib/dynamodb-client"
+	"testing"
+)
+
+func TestListKeyword(t *testing.T) {
+	list := []interface{}{"a", "b"}
+
+	tests := []struct {
+		name      string
+		condition dc.Condition
+		wantErr   bool
+	}{
+		{"const", dc.Eq("Labels", list), false},
+		{"not_equal", dc.Ne("Labels", list), false},
+		{"keyword", dc.Contains("Labels", list), true},
+		{"not_contains", dc.NotContains("Labels", list), true},
+		{"gt", dc.Gt("Labels", list), true},
+		{"in", dc.In("Labels", list, "c"), true},
+		{"between", dc.Between("Labels", list, "c"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t)
+
+			record := struct {
+				dc.DynamoDbMetaData
+				Labels []string `dynamodbav:",stringset"`
+			}{Labels: []string{"a", "b"}}
+			record.PK, record.SK = "P", "S#00"
+
+			if err := client.Insert(record); err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+
+			items, _, err := client.GetItemList(dc.Key{PK: aws.String("P")}, "", dc.QueryOption{Condition: tt.condition})
+			if tt.wantErr {
+				if !errors.Is(err, dc.ErrInvalidFilter) {
+					t.Errorf("GetItemList() error = %v, want ErrInvalidFilter", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("GetItemList() error = %v", err)
+			}
+
+			if want := map[string]int{"const": 1, "not_equal": 0}[tt.name]; want != len(items) {
+				t.Errorf("GetItemList() = %d item(s), want %d", len(items), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package dynamodb_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"gitlab.com/ptami_lib/util"
+)
+
+// GetItemAs fetches a single item like GetItem and unmarshals it into T,
+// typically a struct embedding DynamoDbMetaData.
+func GetItemAs[T any](r *DynamoDbClient, key Key, options ...ReadOption) (item T, err error) {
+	return GetItemAsCtx[T](context.TODO(), r, key, options...)
+}
+
+func GetItemAsCtx[T any](ctx context.Context, r *DynamoDbClient, key Key, options ...ReadOption) (item T, err error) {
+	var notFound *NotFoundError
+
+	av, err := r.GetItemCtx(ctx, key, options...)
+	if errors.As(err, &notFound) {
+		// the error already names the key
+		err = fmt.Errorf("get item: %w", err)
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("get item (%s): %w", util.StructToString(key), err)
+		return
+	}
+
+	if err = attributevalue.UnmarshalMap(av, &item); err != nil {
+		err = fmt.Errorf("unmarshal item (%s): %w", util.StructToString(key), err)
+		return
+	}
+
+	return
+}
+
+// ListItemsAs queries items like GetItemList and unmarshals them into a slice
+// of T.
+func ListItemsAs[T any](r *DynamoDbClient, key Key, arrayOfField string, queryOption QueryOption, options ...ReadOption) (items []T, lastEvaluatedKey Cursor, err error) {
+	return ListItemsAsCtx[T](context.TODO(), r, key, arrayOfField, queryOption, options...)
+}
+
+func ListItemsAsCtx[T any](ctx context.Context, r *DynamoDbClient, key Key, arrayOfField string, queryOption QueryOption, options ...ReadOption) (items []T, lastEvaluatedKey Cursor, err error) {
+	avs, lastEvaluatedKey, err := r.GetItemListCtx(ctx, key, arrayOfField, queryOption, options...)
+	if err != nil {
+		err = fmt.Errorf("get item list (%s): %w", util.StructToString(key), err)
+		return
+	}
+
+	if err = attributevalue.UnmarshalListOfMaps(avs, &items); err != nil {
+		err = fmt.Errorf("unmarshal item list (%s): %w", util.StructToString(key), err)
+		return
+	}
+
+	return
+}
@@ -1,11 +1,13 @@
 module gitlab.com/ptami_lib/dynamodb-client
 
-go 1.17
+go 1.18
 
 require (
 	github.com/aws/aws-sdk-go-v2 v1.16.16
 	github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue v1.10.0
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.17.1
+	github.com/aws/smithy-go v1.13.3
+	github.com/oklog/ulid/v2 v2.0.2
 	github.com/opensearch-project/opensearch-go v1.1.0
 	gitlab.com/ptami_lib/log/v2 v2.0.0-alpah2
 	gitlab.com/ptami_lib/util v1.0.15
@@ -30,11 +32,9 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.26.6 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sso v1.11.4 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sts v1.16.4 // indirect
-	github.com/aws/smithy-go v1.13.3 // indirect
 	github.com/jinzhu/gorm v1.9.16 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	github.com/lib/pq v1.1.1 // indirect
-	github.com/oklog/ulid/v2 v2.0.2 // indirect
 	github.com/sendgrid/rest v2.6.4+incompatible // indirect
 	github.com/sendgrid/sendgrid-go v3.10.0+incompatible // indirect
 )
@@ -0,0 +1,135 @@
+package dynamodb_client
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ItemIterator walks the items of a GetItemList query, fetching one page at a
+// time only when the previous one is consumed:
+//
+//	it := client.NewItemIterator(key, "", queryOption, 0)
+//	defer it.Close()
+//	for it.Next() {
+//		process(it.Item())
+//	}
+//	err := it.Err()
+type ItemIterator struct {
+	r        *DynamoDbClient
+	ctx      context.Context
+	input    *dynamodb.QueryInput
+	maxItems int
+	items    []map[string]types.AttributeValue
+	item     map[string]types.AttributeValue
+	count    int
+	started  bool
+	done     bool
+	err      error
+}
+
+// NewItemIterator iterates over the items GetItemList would return for key and
+// queryOption, stopping after maxItems items when maxItems is positive.
+// QueryOptionPage.PageSize bounds each request and QueryOptionPage.LastEvaluatedKey
+// sets where to start; AllInOne is ignored. An Order that can only be applied
+// on the client is rejected with ErrInvalidOrder.
+func (r *DynamoDbClient) NewItemIterator(key Key, arrayOfField string, queryOption QueryOption, maxItems int, options ...ReadOption) *ItemIterator {
+	return r.NewItemIteratorCtx(context.TODO(), key, arrayOfField, queryOption, maxItems, options...)
+}
+
+func (r *DynamoDbClient) NewItemIteratorCtx(ctx context.Context, key Key, arrayOfField string, queryOption QueryOption, maxItems int, options ...ReadOption) *ItemIterator {
+	it := &ItemIterator{
+		r:        r,
+		ctx:      ctx,
+		maxItems: maxItems,
+	}
+
+	input, binding, sortOnClient, err := r.buildItemListInput(key, arrayOfField, queryOption, newReadOption(options))
+	if err != nil {
+		it.err = err
+		return it
+	}
+
+	if sortOnClient {
+		it.err = &InvalidOrderError{Field: queryOption.Order[0].Field, Reason: "ordering by a field other than the index sort key is not supported by ItemIterator"}
+		return it
+	}
+
+	if nil != queryOption.Page {
+		if queryOption.Page.PageSize > 0 {
+			input.Limit = aws.Int32(int32(queryOption.Page.PageSize))
+		}
+
+		input.ExclusiveStartKey, err = r.decodeLastEvaluatedKey(binding, queryCursorPartition(key), queryOption.Page.LastEvaluatedKey)
+		if err != nil {
+			it.err = err
+			return it
+		}
+	}
+
+	it.input = input
+
+	return it
+}
+
+// Next advances to the next item, fetching the next page when needed. It
+// returns false once the items, maxItems or the context are exhausted, or on
+// error.
+func (it *ItemIterator) Next() bool {
+	it.item = nil
+
+	if nil != it.err || it.done {
+		return false
+	}
+
+	if it.maxItems > 0 && it.count >= it.maxItems {
+		it.done = true
+		return false
+	}
+
+	for 0 == len(it.items) {
+		if it.started && nil == it.input.ExclusiveStartKey {
+			it.done = true
+			return false
+		}
+
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+
+		output, err := it.r.dynamoDb.Query(it.ctx, it.input)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		it.started = true
+		it.items = output.Items
+		it.input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+
+	it.item = it.items[0]
+	it.items = it.items[1:]
+	it.count++
+
+	return true
+}
+
+// Item returns the current item, valid after Next returned true.
+func (it *ItemIterator) Item() map[string]types.AttributeValue {
+	return it.item
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *ItemIterator) Err() error {
+	return it.err
+}
+
+// Close stops the iteration early; no further page is fetched.
+func (it *ItemIterator) Close() {
+	it.done = true
+	it.items = nil
+	it.item = nil
+}
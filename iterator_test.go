@@ -0,0 +1,122 @@
+package dynamodb_client_test
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	dc "gitlab.com/ptami_lib/dynamodb-client"
+	"gitlab.com/ptami_lib/dynamodb-client/dynamodbtest"
+	"reflect"
+	"testing"
+)
+
+// iterate collects the Count of every item of it.
+func iterate(t *testing.T, it *dc.ItemIterator) (got []int) {
+	t.Helper()
+
+	for it.Next() {
+		var record testRecord
+
+		if err := attributevalue.UnmarshalMap(it.Item(), &record); err != nil {
+			t.Fatalf("unmarshal error = %v", err)
+		}
+
+		got = append(got, record.Count)
+	}
+
+	return
+}
+
+func TestItemIterator(t *testing.T) {
+	tests := []struct {
+		name     string
+		pageSize uint
+		maxItems int
+		want     []int
+		queries  int
+	}{
+		{"every page", 2, 0, []int{4, 3, 2, 1, 0}, 3},
+		{"max items across pages", 2, 3, []int{4, 3, 2}, 2},
+		{"max items on a page boundary", 2, 2, []int{4, 3}, 1},
+		{"single page", 10, 0, []int{4, 3, 2, 1, 0}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &recordingTable{Table: dynamodbtest.New("t")}
+			client := dc.New(table, "t")
+			insertTestRecords(t, client, 5)
+
+			it := client.NewItemIterator(listKey(), "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: tt.pageSize}}, tt.maxItems)
+			defer it.Close()
+
+			if got := iterate(t, it); !reflect.DeepEqual(got, tt.want) || nil != it.Err() {
+				t.Errorf("iterator = %v, %v, want %v", got, it.Err(), tt.want)
+			}
+
+			if tt.queries != len(table.queries) {
+				t.Errorf("iterator sent %d queries, want %d", len(table.queries), tt.queries)
+			}
+		})
+	}
+}
+
+func TestItemIteratorClose(t *testing.T) {
+	table := &recordingTable{Table: dynamodbtest.New("t")}
+	client := dc.New(table, "t")
+	insertTestRecords(t, client, 5)
+
+	it := client.NewItemIterator(listKey(), "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: 2}}, 0)
+
+	if !it.Next() || nil == it.Item() {
+		t.Fatalf("Next() = false, %v", it.Err())
+	}
+
+	it.Close()
+
+	if it.Next() || nil != it.Item() || nil != it.Err() {
+		t.Errorf("Next() after Close() = true, %v, %v", it.Item(), it.Err())
+	}
+
+	if 1 != len(table.queries) {
+		t.Errorf("iterator sent %d queries after Close(), want 1", len(table.queries))
+	}
+}
+
+func TestItemIteratorCursor(t *testing.T) {
+	client, _ := newTestClient(t)
+	insertTestRecords(t, client, 5)
+
+	_, cursor, err := client.GetItemList(listKey(), "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: 2}})
+	if err != nil || "" == cursor {
+		t.Fatalf("GetItemList() = %q, %v", cursor, err)
+	}
+
+	it := client.NewItemIterator(listKey(), "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: 2, LastEvaluatedKey: cursor}}, 0)
+	defer it.Close()
+
+	if got := iterate(t, it); !reflect.DeepEqual(got, []int{2, 1, 0}) || nil != it.Err() {
+		t.Errorf("iterator = %v, %v, want [2 1 0]", got, it.Err())
+	}
+
+	it = client.NewItemIterator(dc.Key{PK: aws.String("Q"), IndexName: aws.String("GSI1")}, "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: 2, LastEvaluatedKey: cursor}}, 0)
+	if it.Next() || !errors.Is(it.Err(), dc.ErrInvalidCursor) {
+		t.Errorf("iterator of another query error = %v, want ErrInvalidCursor", it.Err())
+	}
+}
+
+func TestItemIteratorOrder(t *testing.T) {
+	table := &recordingTable{Table: dynamodbtest.New("t")}
+	client := dc.New(table, "t")
+	insertTestRecords(t, client, 3)
+
+	it := client.NewItemIterator(listKey(), "", dc.QueryOption{Order: []dc.QueryOptionOrder{{Field: "Count"}}}, 0)
+	if it.Next() || !errors.Is(it.Err(), dc.ErrInvalidOrder) || 0 != len(table.queries) {
+		t.Errorf("iterator ordered on the client error = %v after %d queries, want ErrInvalidOrder", it.Err(), len(table.queries))
+	}
+
+	it = client.NewItemIterator(listKey(), "", dc.QueryOption{Order: []dc.QueryOptionOrder{{Field: "GSI1SK", Direction: dc.QueryOptionOrderDirectionAsc}}}, 0)
+	if got := iterate(t, it); !reflect.DeepEqual(got, []int{0, 1, 2}) || nil != it.Err() {
+		t.Errorf("iterator ordered on the sort key = %v, %v, want [0 1 2]", got, it.Err())
+	}
+}
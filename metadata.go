@@ -0,0 +1,85 @@
+package dynamodb_client
+
+import (
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"reflect"
+)
+
+var dynamoDbMetaDataType = reflect.TypeOf(DynamoDbMetaData{})
+
+// marshalItem marshals an item to put, first filling the missing Id,
+// CreatedTimestamp and UpdatedTimestamp of the DynamoDbMetaData it embeds,
+// which is returned when found. Items passed by pointer are filled in place,
+// others are filled on a copy.
+func (r *DynamoDbClient) marshalItem(item interface{}) (avItem map[string]types.AttributeValue, metaData *DynamoDbMetaData, err error) {
+	v := reflect.ValueOf(item)
+
+	if reflect.Ptr == v.Kind() && !v.IsNil() {
+		v = v.Elem()
+	} else if reflect.Struct == v.Kind() {
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+		item = c.Addr().Interface()
+	}
+
+	if metaData = findMetaData(v); nil != metaData {
+		if err = r.fillMetaData(metaData); err != nil {
+			return
+		}
+	}
+
+	avItem, err = attributevalue.MarshalMap(item)
+
+	return
+}
+
+func findMetaData(v reflect.Value) *DynamoDbMetaData {
+	if reflect.Struct != v.Kind() || !v.CanAddr() {
+		return nil
+	}
+
+	if dynamoDbMetaDataType == v.Type() {
+		return v.Addr().Interface().(*DynamoDbMetaData)
+	}
+
+	field, ok := v.Type().FieldByName(dynamoDbMetaDataType.Name())
+	if !ok || !field.Anonymous || dynamoDbMetaDataType != field.Type {
+		return nil
+	}
+
+	f, err := v.FieldByIndexErr(field.Index)
+	if err != nil {
+		return nil
+	}
+
+	return f.Addr().Interface().(*DynamoDbMetaData)
+}
+
+func (r *DynamoDbClient) fillMetaData(metaData *DynamoDbMetaData) (err error) {
+	now := r.clock.Now()
+
+	if nil == metaData.Id || "" == *metaData.Id {
+		var id string
+
+		id, err = r.idGenerator.NewId(now)
+		if err != nil {
+			return
+		}
+
+		metaData.Id = &id
+	}
+
+	if nil == metaData.CreatedTimestamp {
+		createdTimestamp := now
+		metaData.CreatedTimestamp = &createdTimestamp
+	}
+
+	if nil == metaData.UpdatedTimestamp {
+		updatedTimestamp := now
+		metaData.UpdatedTimestamp = &updatedTimestamp
+	}
+
+	return
+}
@@ -22,6 +22,7 @@ type DynamoDbMetaData struct {
 	Id               *string    `json:",omitempty" dynamodbav:",omitempty"`
 	CreatedTimestamp *time.Time `json:",omitempty" dynamodbav:",omitempty"`
 	UpdatedTimestamp *time.Time `json:",omitempty" dynamodbav:",omitempty"`
+	Version          *uint64    `json:",omitempty" dynamodbav:",omitempty"`
 }
 
 // DynamoDbValueMetaData
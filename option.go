@@ -0,0 +1,93 @@
+package dynamodb_client
+
+import (
+	cryptorand "crypto/rand"
+	"github.com/oklog/ulid/v2"
+	"math/rand"
+	"time"
+)
+
+type Option func(*DynamoDbClient)
+
+// RetryPolicy controls how unprocessed items of batch requests are retried:
+// attempt n waits a random delay between half and all of
+// min(MaxDelay, BaseDelay * 2^n).
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 8,
+	BaseDelay:   50 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+func WithRetryPolicy(retryPolicy RetryPolicy) Option {
+	return func(r *DynamoDbClient) {
+		r.retryPolicy = retryPolicy
+	}
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+
+	if attempt < 32 && p.BaseDelay<<uint(attempt) < p.MaxDelay {
+		delay = p.BaseDelay << uint(attempt)
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function such as time.Now to a Clock.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// IdGenerator generates the Id of inserted items, now being the time given by
+// the client's Clock.
+type IdGenerator interface {
+	NewId(now time.Time) (string, error)
+}
+
+// IdGeneratorFunc adapts a function to an IdGenerator.
+type IdGeneratorFunc func(now time.Time) (string, error)
+
+func (f IdGeneratorFunc) NewId(now time.Time) (string, error) {
+	return f(now)
+}
+
+// UlidGenerator generates ULIDs from now and crypto/rand entropy.
+var UlidGenerator IdGenerator = IdGeneratorFunc(func(now time.Time) (id string, err error) {
+	u, err := ulid.New(ulid.Timestamp(now), cryptorand.Reader)
+	if err != nil {
+		return
+	}
+
+	id = u.String()
+
+	return
+})
+
+func WithClock(clock Clock) Option {
+	return func(r *DynamoDbClient) {
+		r.clock = clock
+	}
+}
+
+func WithIdGenerator(idGenerator IdGenerator) Option {
+	return func(r *DynamoDbClient) {
+		r.idGenerator = idGenerator
+	}
+}
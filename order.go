@@ -0,0 +1,142 @@
+package dynamodb_client
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	QueryOptionOrderDirectionAsc  = "asc"
+	QueryOptionOrderDirectionDesc = "desc"
+)
+
+// resolveQueryOptionOrder returns the ScanIndexForward honouring order when it
+// is a single order on the sort key of the index, and otherwise reports that
+// the items must be sorted on the client once all pages are read.
+func resolveQueryOptionOrder(indexName string, order []QueryOptionOrder) (scanIndexForward *bool, sortOnClient bool, err error) {
+	for _, o := range order {
+		if "" == o.Field {
+			err = &InvalidOrderError{Reason: "field is required"}
+			return
+		}
+
+		switch strings.ToLower(o.Direction) {
+		case "", QueryOptionOrderDirectionAsc, QueryOptionOrderDirectionDesc:
+		default:
+			err = &InvalidOrderError{Field: o.Field, Reason: fmt.Sprintf("unsupported direction (%s)", o.Direction)}
+			return
+		}
+	}
+
+	if 1 == len(order) && fmt.Sprintf("%sSK", indexName) == order[0].Field {
+		scanIndexForward = aws.Bool(QueryOptionOrderDirectionDesc != strings.ToLower(order[0].Direction))
+		return
+	}
+
+	sortOnClient = len(order) > 0
+
+	return
+}
+
+// sortItems stable sorts items by every order in turn; a missing attribute
+// sorts before any value.
+func sortItems(items []map[string]types.AttributeValue, order []QueryOptionOrder) {
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, o := range order {
+			path := strings.Split(o.Field, ".")
+			cmp := compareAttributeValues(lookupAttributeValue(items[i], path), lookupAttributeValue(items[j], path))
+
+			if 0 != cmp {
+				if QueryOptionOrderDirectionDesc == strings.ToLower(o.Direction) {
+					return cmp > 0
+				}
+
+				return cmp < 0
+			}
+		}
+
+		return false
+	})
+}
+
+func lookupAttributeValue(item map[string]types.AttributeValue, path []string) (value types.AttributeValue) {
+	value = &types.AttributeValueMemberM{Value: item}
+
+	for _, name := range path {
+		m, ok := value.(*types.AttributeValueMemberM)
+		if !ok {
+			return nil
+		}
+
+		if value, ok = m.Value[name]; !ok {
+			return nil
+		}
+	}
+
+	return
+}
+
+// compareAttributeValues orders scalars of the same type by value, and
+// values of different types by type.
+func compareAttributeValues(a types.AttributeValue, b types.AttributeValue) int {
+	ra, rb := attributeValueRank(a), attributeValueRank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+
+		return 1
+	}
+
+	switch va := a.(type) {
+	case *types.AttributeValueMemberS:
+		return strings.Compare(va.Value, b.(*types.AttributeValueMemberS).Value)
+	case *types.AttributeValueMemberN:
+		fa, _ := strconv.ParseFloat(va.Value, 64)
+		fb, _ := strconv.ParseFloat(b.(*types.AttributeValueMemberN).Value, 64)
+
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+	case *types.AttributeValueMemberB:
+		return bytes.Compare(va.Value, b.(*types.AttributeValueMemberB).Value)
+	case *types.AttributeValueMemberBOOL:
+		vb := b.(*types.AttributeValueMemberBOOL).Value
+
+		switch {
+		case !va.Value && vb:
+			return -1
+		case va.Value && !vb:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func attributeValueRank(value types.AttributeValue) int {
+	switch value.(type) {
+	case nil:
+		return 0
+	case *types.AttributeValueMemberNULL:
+		return 1
+	case *types.AttributeValueMemberBOOL:
+		return 2
+	case *types.AttributeValueMemberN:
+		return 3
+	case *types.AttributeValueMemberS:
+		return 4
+	case *types.AttributeValueMemberB:
+		return 5
+	}
+
+	return 6
+}
@@ -0,0 +1,83 @@
+package dynamodb_client_test
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dc "gitlab.com/ptami_lib/dynamodb-client"
+	"gitlab.com/ptami_lib/dynamodb-client/dynamodbtest"
+	"reflect"
+	"testing"
+)
+
+func TestOrderOnSortKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         dc.Key
+		queryOption dc.QueryOption
+		wantForward bool
+		want        []int
+	}{
+		{"asc", listKey(), dc.QueryOption{Order: []dc.QueryOptionOrder{{Field: "GSI1SK", Direction: dc.QueryOptionOrderDirectionAsc}}}, true, []int{0, 1, 2, 3}},
+		{"desc", listKey(), dc.QueryOption{Order: []dc.QueryOptionOrder{{Field: "GSI1SK", Direction: dc.QueryOptionOrderDirectionDesc}}}, false, []int{3, 2, 1, 0}},
+		{"default direction", listKey(), dc.QueryOption{Order: []dc.QueryOptionOrder{{Field: "GSI1SK"}}}, true, []int{0, 1, 2, 3}},
+		{"over ScanIndexForward", listKey(), dc.QueryOption{
+			Order:            []dc.QueryOptionOrder{{Field: "GSI1SK", Direction: dc.QueryOptionOrderDirectionDesc}},
+			ScanIndexForward: aws.Bool(true),
+		}, false, []int{3, 2, 1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &recordingTable{Table: dynamodbtest.New("t")}
+			client := dc.New(table, "t")
+			insertTestRecords(t, client, 4)
+
+			// paging rules out a sort on the client, which requires AllInOne
+			tt.queryOption.Page = &dc.QueryOptionPage{PageSize: 10}
+
+			got, _, err := dc.ListItemsAs[testRecord](client, tt.key, "", tt.queryOption)
+			if err != nil {
+				t.Fatalf("ListItemsAs() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(counts(got), tt.want) {
+				t.Errorf("ListItemsAs() = %v, want %v", counts(got), tt.want)
+			}
+
+			if 1 != len(table.queries) || tt.wantForward != aws.ToBool(table.queries[0].ScanIndexForward) {
+				t.Errorf("ScanIndexForward = %v, want %v", aws.ToBool(table.queries[0].ScanIndexForward), tt.wantForward)
+			}
+		})
+	}
+}
+
+func TestOrderOnClient(t *testing.T) {
+	client, _ := newTestClient(t)
+	insertTestRecords(t, client, 6)
+
+	order := []dc.QueryOptionOrder{{Field: "Status", Direction: dc.QueryOptionOrderDirectionDesc}, {Field: "Count"}}
+
+	got, _, err := dc.ListItemsAs[testRecord](client, listKey(), "", dc.QueryOption{Order: order, Page: &dc.QueryOptionPage{AllInOne: true}})
+	if err != nil || !reflect.DeepEqual(counts(got), []int{2, 5, 1, 4, 0, 3}) {
+		t.Errorf("ListItemsAs() = %v, %v, want [2 5 1 4 0 3]", counts(got), err)
+	}
+
+	tests := []struct {
+		name        string
+		queryOption dc.QueryOption
+	}{
+		{"paged", dc.QueryOption{Order: order, Page: &dc.QueryOptionPage{PageSize: 2}}},
+		{"sort key among others", dc.QueryOption{Order: []dc.QueryOptionOrder{{Field: "GSI1SK"}, {Field: "Count"}}, Page: &dc.QueryOptionPage{PageSize: 2}}},
+		{"missing field", dc.QueryOption{Order: []dc.QueryOptionOrder{{Direction: dc.QueryOptionOrderDirectionAsc}}}},
+		{"unknown direction", dc.QueryOption{Order: []dc.QueryOptionOrder{{Field: "GSI1SK", Direction: "up"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := client.GetItemList(listKey(), "", tt.queryOption)
+			if !errors.Is(err, dc.ErrInvalidOrder) {
+				t.Errorf("GetItemList() error = %v, want ErrInvalidOrder", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package dynamodb_client
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"regexp"
+	"strings"
+)
+
+var listIndexesPattern = regexp.MustCompile(`^(\[[0-9]+])*$`)
+
+// placeholderAllocator hands out the #n and :v placeholders of the names and
+// values of an expression. Placeholders already present in the maps it fills,
+// such as those of a key condition or of the update a condition is added to,
+// are never reused for another name or value.
+type placeholderAllocator struct {
+	expressionAttributeNames  map[string]string
+	expressionAttributeValues map[string]types.AttributeValue
+	nameTokens                map[string]string
+	nextName                  int
+	nextValue                 int
+	zeroToken                 string
+}
+
+func newPlaceholderAllocator(expressionAttributeNames map[string]string, expressionAttributeValues map[string]types.AttributeValue) *placeholderAllocator {
+	a := &placeholderAllocator{
+		expressionAttributeNames:  expressionAttributeNames,
+		expressionAttributeValues: expressionAttributeValues,
+		nameTokens:                make(map[string]string, len(expressionAttributeNames)),
+	}
+
+	for token, name := range expressionAttributeNames {
+		if existing, ok := a.nameTokens[name]; !ok || token < existing {
+			a.nameTokens[name] = token
+		}
+	}
+
+	return a
+}
+
+// name returns the placeholder of the attribute name, the same one for every
+// use of name.
+func (a *placeholderAllocator) name(name string) string {
+	if token, ok := a.nameTokens[name]; ok {
+		return token
+	}
+
+	token := fmt.Sprintf("#n%d", a.nextName)
+	for _, used := a.expressionAttributeNames[token]; used; _, used = a.expressionAttributeNames[token] {
+		a.nextName++
+		token = fmt.Sprintf("#n%d", a.nextName)
+	}
+
+	a.nextName++
+	a.expressionAttributeNames[token] = name
+	a.nameTokens[name] = token
+
+	return token
+}
+
+// value returns a new placeholder holding av.
+func (a *placeholderAllocator) value(av types.AttributeValue) string {
+	token := fmt.Sprintf(":v%d", a.nextValue)
+	for _, used := a.expressionAttributeValues[token]; used; _, used = a.expressionAttributeValues[token] {
+		a.nextValue++
+		token = fmt.Sprintf(":v%d", a.nextValue)
+	}
+
+	a.nextValue++
+	a.expressionAttributeValues[token] = av
+
+	return token
+}
+
+// zero returns the placeholder of the number 0, allocated once.
+func (a *placeholderAllocator) zero() string {
+	if "" == a.zeroToken {
+		a.zeroToken = a.value(&types.AttributeValueMemberN{Value: "0"})
+	}
+
+	return a.zeroToken
+}
+
+// path returns the placeholders of a document path such as "a.b[0].c", every
+// attribute name being replaced by its placeholder and list indexes kept.
+func (a *placeholderAllocator) path(path string) (expression string, err error) {
+	if "" == path {
+		err = &InvalidPathError{Path: path, Reason: "empty path"}
+		return
+	}
+
+	segments := strings.Split(path, ".")
+	for i, segment := range segments {
+		name, indexes := segment, ""
+		if at := strings.IndexByte(segment, '['); at >= 0 {
+			name, indexes = segment[:at], segment[at:]
+		}
+
+		if "" == name || strings.ContainsAny(name, "]") {
+			err = &InvalidPathError{Path: path, Reason: fmt.Sprintf("invalid attribute name (%s)", segment)}
+			return
+		}
+
+		if !listIndexesPattern.MatchString(indexes) {
+			err = &InvalidPathError{Path: path, Reason: fmt.Sprintf("invalid list index (%s)", segment)}
+			return
+		}
+
+		segments[i] = a.name(name) + indexes
+	}
+
+	expression = strings.Join(segments, ".")
+
+	return
+}
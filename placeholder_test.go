@@ -0,0 +1,146 @@
+package dynamodb_client_test
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	dc "gitlab.com/ptami_lib/dynamodb-client"
+	"gitlab.com/ptami_lib/dynamodb-client/dynamodbtest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type pathRecord struct {
+	dc.DynamoDbMetaData
+	A     map[string]int `dynamodbav:"a"`
+	AB    int            `dynamodbav:"ab"`
+	Items []string
+}
+
+func TestFilterPaths(t *testing.T) {
+	table := &recordingTable{Table: dynamodbtest.New("t")}
+	client := dc.New(table, "t")
+
+	record := pathRecord{A: map[string]int{"b": 1}, AB: 2, Items: []string{"x", "y"}}
+	record.PK, record.SK = "P", "S#00"
+	record.GSI1PK, record.GSI1SK = aws.String("G"), aws.String("G#00")
+
+	if err := client.Insert(record); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		condition dc.Condition
+		want      int
+	}{
+		{"nested and flat", dc.And(dc.Eq("a.b", 1), dc.Eq("ab", 2)), 1},
+		{"nested and flat swapped", dc.And(dc.Eq("a.b", 2), dc.Eq("ab", 1)), 0},
+		{"list index", dc.Eq("Items[1]", "y"), 1},
+		{"list index mismatch", dc.Eq("Items[0]", "y"), 0},
+		{"filter map", mustConditionFromFilter(t, map[string]interface{}{
+			"nested": map[string]interface{}{"field": "a.b", "type": dc.FilterTypeConst, "keyword": 1},
+			"flat":   map[string]interface{}{"field": "ab", "type": dc.FilterTypeConst, "keyword": 2},
+		}), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, _, err := client.GetItemList(listKey(), "", dc.QueryOption{Condition: tt.condition})
+			if err != nil || tt.want != len(items) {
+				t.Errorf("GetItemList() = %d item(s), %v, want %d", len(items), err, tt.want)
+			}
+		})
+	}
+
+	_, _, err := client.GetItemList(listKey(), "", dc.QueryOption{Condition: dc.And(dc.Eq("a.b", 1), dc.Eq("ab", 2))})
+	if err != nil {
+		t.Fatalf("GetItemList() error = %v", err)
+	}
+
+	var names []string
+	for _, name := range table.queries[len(table.queries)-1].ExpressionAttributeNames {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	if want := []string{"GSI1PK", "a", "ab", "b"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ExpressionAttributeNames = %v, want one placeholder for each of %v", names, want)
+	}
+
+	_, _, err = client.GetItemList(listKey(), "", dc.QueryOption{Filter: map[string]interface{}{
+		"malformed": map[string]interface{}{"field": "a..b", "type": dc.FilterTypeConst, "keyword": 1},
+	}})
+	if !errors.Is(err, dc.ErrInvalidFilter) || !errors.Is(err, dc.ErrInvalidPath) {
+		t.Errorf("GetItemList() of a malformed path error = %v, want ErrInvalidFilter and ErrInvalidPath", err)
+	}
+}
+
+func mustConditionFromFilter(t *testing.T, filter map[string]interface{}) dc.Condition {
+	t.Helper()
+
+	condition, err := dc.ConditionFromFilter(filter)
+	if err != nil {
+		t.Fatalf("ConditionFromFilter() error = %v", err)
+	}
+
+	return condition
+}
+
+func TestUpdatePaths(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	record := pathRecord{A: map[string]int{"b": 1}, AB: 2, Items: []string{"x", "y"}}
+	record.PK, record.SK = "P", "S#00"
+
+	if err := client.Insert(record); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	_, err := client.UpdateItem(testKey("S#00"), map[string]interface{}{
+		"Items[1]":        "z",
+		"a.b":             3,
+		"Fn:increase:ab":  1,
+		"Fn:increase:a.c": 5,
+	})
+	if err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+
+	item, err := client.GetItem(testKey("S#00"))
+	if err != nil {
+		t.Fatalf("GetItem() error = %v", err)
+	}
+
+	var got pathRecord
+	if err = attributevalue.UnmarshalMap(item, &got); err != nil {
+		t.Fatalf("unmarshal error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got.A, map[string]int{"b": 3, "c": 5}) || 3 != got.AB || !reflect.DeepEqual(got.Items, []string{"x", "z"}) {
+		t.Errorf("UpdateItem() stored a = %v, ab = %d, Items = %v", got.A, got.AB, got.Items)
+	}
+}
+
+func TestVersionPlaceholders(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	record := &pathRecord{AB: 1}
+	record.PK, record.SK = "P", "S#00"
+
+	if err := client.Insert(record, dc.WithVersion(0)); err != nil {
+		t.Fatalf("Insert(WithVersion(0)) error = %v", err)
+	}
+
+	_, err := client.UpdateItem(testKey("S#00"), map[string]interface{}{"ab": 2}, dc.WithVersion(1), dc.WithCondition(dc.And(dc.Eq("Version", 1), dc.Eq("ab", 1))))
+	if err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+
+	got, err := dc.GetItemAs[pathRecord](client, testKey("S#00"))
+	if err != nil || 2 != got.AB || nil == got.Version || 2 != *got.Version {
+		t.Errorf("stored item = %+v, %v, want ab 2 and Version 2", got, err)
+	}
+}
@@ -0,0 +1,84 @@
+package dynamodb_client
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"strings"
+)
+
+// ReadOption tunes GetItem, GetItemList, ItemIterator, Scan, ParallelScan and
+// BatchGet.
+type ReadOption func(*readOption)
+
+type readOption struct {
+	projection     []string
+	consistentRead bool
+}
+
+// WithProjection only reads the document paths listed, such as "Name",
+// "ValueData.color" or "Items[0]", in addition to those of arrayOfField.
+func WithProjection(paths ...string) ReadOption {
+	return func(o *readOption) {
+		o.projection = append(o.projection, paths...)
+	}
+}
+
+// WithConsistentRead reads the latest committed data instead of an eventually
+// consistent copy. Global secondary indexes do not support it, so reading one
+// fails with ErrConsistentReadOnIndex.
+func WithConsistentRead() ReadOption {
+	return func(o *readOption) {
+		o.consistentRead = true
+	}
+}
+
+// consistentReadInput is the ConsistentRead of a request, nil unless
+// WithConsistentRead is used.
+func (o readOption) consistentReadInput() *bool {
+	if o.consistentRead {
+		return aws.Bool(true)
+	}
+
+	return nil
+}
+
+func newReadOption(options []ReadOption) (o readOption) {
+	for _, option := range options {
+		option(&o)
+	}
+
+	return
+}
+
+// projectionOf lists the paths of the comma-separated arrayOfField followed by
+// those of WithProjection, nil meaning every attribute.
+func (o readOption) projectionOf(arrayOfField string) (paths []string) {
+	for _, field := range strings.Split(arrayOfField, ",") {
+		if "" != strings.TrimSpace(field) {
+			paths = append(paths, field)
+		}
+	}
+
+	return append(paths, o.projection...)
+}
+
+// buildProjection builds the projection of the paths of arrayOfField and of
+// WithProjection, adding required when any is set, for the requests that do not
+// share their names with other expressions.
+func (o readOption) buildProjection(arrayOfField string, required ...string) (projectionExpression *string, expressionAttributeNames map[string]string, err error) {
+	var projection = o.projectionOf(arrayOfField)
+
+	if 0 == len(projection) {
+		return
+	}
+
+	expressionAttributeNames = map[string]string{}
+
+	expression, err := buildProjectionExpression(append(projection, required...), expressionAttributeNames)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	projectionExpression = aws.String(expression)
+
+	return
+}
@@ -0,0 +1,143 @@
+package dynamodb_client_test
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	dc "gitlab.com/ptami_lib/dynamodb-client"
+	"gitlab.com/ptami_lib/dynamodb-client/dynamodbtest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func insertProjectionRecord(t *testing.T, client *dc.DynamoDbClient) {
+	t.Helper()
+
+	record := testRecord{Status: "A", Count: 1, Tags: []string{"a", "b"}, Info: map[string]string{"City": "Paris", "Zip": "75000"}}
+	record.PK, record.SK = "P", "S#00"
+	record.GSI1PK, record.GSI1SK = aws.String("G"), aws.String("G#00")
+
+	if err := client.Insert(record); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+}
+
+// checkProjection checks that item only holds the attributes want, Info
+// reduced to its City and Tags to its second entry when listed.
+func checkProjection(t *testing.T, item map[string]types.AttributeValue, want ...string) {
+	t.Helper()
+
+	var got []string
+	for name := range item {
+		got = append(got, name)
+	}
+
+	sort.Strings(got)
+	sort.Strings(want)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("attributes = %v, want %v", got, want)
+	}
+
+	if info, ok := item["Info"]; ok && !reflect.DeepEqual(info, &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+		"City": &types.AttributeValueMemberS{Value: "Paris"},
+	}}) {
+		t.Errorf("Info = %v, want only City", info)
+	}
+
+	if tags, ok := item["Tags"]; ok && !reflect.DeepEqual(tags, &types.AttributeValueMemberL{Value: []types.AttributeValue{
+		&types.AttributeValueMemberS{Value: "b"},
+	}}) {
+		t.Errorf("Tags = %v, want [b]", tags)
+	}
+}
+
+func TestWithProjection(t *testing.T) {
+	client, _ := newTestClient(t)
+	insertProjectionRecord(t, client)
+
+	projection := dc.WithProjection("Info.City", "Tags[1]")
+
+	item, err := client.GetItem(testKey("S#00"), projection)
+	if err != nil {
+		t.Fatalf("GetItem() error = %v", err)
+	}
+
+	checkProjection(t, item, "Info", "Tags")
+
+	item, err = client.GetItem(dc.Key{PK: aws.String("G"), SK: aws.String("G#00"), IndexName: aws.String("GSI1")}, projection)
+	if err != nil {
+		t.Fatalf("GetItem() on GSI1 error = %v", err)
+	}
+
+	checkProjection(t, item, "Info", "Tags")
+
+	items, _, err := client.GetItemList(listKey(), "Status", dc.QueryOption{}, projection)
+	if err != nil || 1 != len(items) {
+		t.Fatalf("GetItemList() = %d item(s), %v", len(items), err)
+	}
+
+	checkProjection(t, items[0], "Info", "Status", "Tags")
+
+	items, err = client.BatchGet([]dc.Key{testKey("S#00")}, "", projection)
+	if err != nil || 1 != len(items) {
+		t.Fatalf("BatchGet() = %d item(s), %v", len(items), err)
+	}
+
+	checkProjection(t, items[0], "Info", "PK", "SK", "Tags")
+
+	items, _, err = client.Scan(nil, "", dc.QueryOption{}, projection)
+	if err != nil || 1 != len(items) {
+		t.Fatalf("Scan() = %d item(s), %v", len(items), err)
+	}
+
+	checkProjection(t, items[0], "Info", "Tags")
+
+	_, err = client.GetItem(testKey("S#00"), dc.WithProjection("Info..City"))
+	if !errors.Is(err, dc.ErrInvalidPath) {
+		t.Errorf("GetItem() of a malformed path error = %v, want ErrInvalidPath", err)
+	}
+}
+
+func TestWithConsistentRead(t *testing.T) {
+	table := &recordingTable{Table: dynamodbtest.New("t")}
+	client := dc.New(table, "t")
+	insertProjectionRecord(t, client)
+
+	indexKey := dc.Key{PK: aws.String("G"), SK: aws.String("G#00"), IndexName: aws.String("GSI1")}
+	consistent := dc.WithConsistentRead()
+
+	if _, err := client.GetItem(testKey("S#00"), consistent); err != nil {
+		t.Errorf("GetItem() error = %v", err)
+	}
+
+	if _, err := client.GetItem(indexKey, consistent); !errors.Is(err, dc.ErrConsistentReadOnIndex) {
+		t.Errorf("GetItem() on GSI1 error = %v, want ErrConsistentReadOnIndex", err)
+	}
+
+	items, _, err := client.GetItemList(dc.Key{PK: aws.String("P")}, "", dc.QueryOption{}, consistent)
+	if err != nil || 1 != len(items) || !aws.ToBool(table.queries[len(table.queries)-1].ConsistentRead) {
+		t.Errorf("GetItemList() = %d item(s), %v, want a consistent query", len(items), err)
+	}
+
+	if _, _, err = client.GetItemList(dc.Key{PK: aws.String("G"), IndexName: aws.String("GSI1")}, "", dc.QueryOption{}, consistent); !errors.Is(err, dc.ErrConsistentReadOnIndex) {
+		t.Errorf("GetItemList() on GSI1 error = %v, want ErrConsistentReadOnIndex", err)
+	}
+
+	if _, _, err = client.GetCountList(dc.Key{PK: aws.String("G"), IndexName: aws.String("GSI1")}, dc.QueryOption{}, consistent); !errors.Is(err, dc.ErrConsistentReadOnIndex) {
+		t.Errorf("GetCountList() on GSI1 error = %v, want ErrConsistentReadOnIndex", err)
+	}
+
+	if items, _, err = client.Scan(nil, "", dc.QueryOption{}, consistent); err != nil || 1 != len(items) {
+		t.Errorf("Scan() = %d item(s), %v", len(items), err)
+	}
+
+	if _, _, err = client.Scan(aws.String("GSI1"), "", dc.QueryOption{}, consistent); !errors.Is(err, dc.ErrConsistentReadOnIndex) {
+		t.Errorf("Scan() on GSI1 error = %v, want ErrConsistentReadOnIndex", err)
+	}
+
+	if items, err = client.BatchGet([]dc.Key{testKey("S#00")}, "", consistent); err != nil || 1 != len(items) || nil == items[0] {
+		t.Errorf("BatchGet() = %v, %v", items, err)
+	}
+}
@@ -0,0 +1,256 @@
+package dynamodb_client
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sync"
+)
+
+// Scan reads the items of the table, or of the index indexName when set,
+// matching the filter of queryOption. As in GetItemList, without
+// queryOption.Page a single page is read and its Cursor returned; reading the
+// whole table takes QueryOptionPage.AllInOne, which Order requires since a
+// scan is always ordered on the client. ScanIndexForward is ignored.
+func (r *DynamoDbClient) Scan(indexName *string, arrayOfField string, queryOption QueryOption, options ...ReadOption) (items []map[string]types.AttributeValue, lastEvaluatedKey Cursor, err error) {
+	return r.ScanCtx(context.TODO(), indexName, arrayOfField, queryOption, options...)
+}
+
+func (r *DynamoDbClient) ScanCtx(ctx context.Context, indexName *string, arrayOfField string, queryOption QueryOption, options ...ReadOption) (items []map[string]types.AttributeValue, lastEvaluatedKey Cursor, err error) {
+	var output *dynamodb.ScanOutput
+	var allInOne = nil != queryOption.Page && queryOption.Page.AllInOne
+	var sortOnClient = len(queryOption.Order) > 0
+
+	if _, _, err = resolveQueryOptionOrder("", queryOption.Order); err != nil {
+		return
+	}
+
+	if sortOnClient && !allInOne {
+		err = &InvalidOrderError{Field: queryOption.Order[0].Field, Reason: "ordering a scan requires AllInOne paging"}
+		return
+	}
+
+	input, err := r.buildScanInput(indexName, arrayOfField, queryOption, newReadOption(options))
+	if err != nil {
+		return
+	}
+
+	binding := scanCursorBinding(indexName)
+
+	if nil != queryOption.Page && !allInOne {
+		input.Limit = aws.Int32(int32(queryOption.Page.PageSize))
+
+		input.ExclusiveStartKey, err = r.decodeLastEvaluatedKey(binding, nil, queryOption.Page.LastEvaluatedKey)
+		if err != nil {
+			return
+		}
+	}
+
+scan:
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	output, err = r.dynamoDb.Scan(ctx, input)
+	if err != nil {
+		return
+	}
+
+	lastEvaluatedKey, err = r.encodeCursor(binding, output.LastEvaluatedKey)
+	if err != nil {
+		return
+	}
+
+	items = append(items, output.Items...)
+
+	if allInOne && nil != output.LastEvaluatedKey {
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+		goto scan
+	}
+
+	if sortOnClient {
+		sortItems(items, queryOption.Order)
+	}
+
+	return
+}
+
+// ParallelScan scans the table, or the index indexName when set, as
+// totalSegments segments read by up to workers goroutines, passing every page
+// of items matching the filter of queryOption to handler. handler may be called
+// concurrently for different segments. The first error, from a scan or from
+// handler, stops the remaining segments and is returned. Segments are read
+// in no particular order from their start, so queryOption.Order fails with
+// ErrInvalidOrder and QueryOptionPage.LastEvaluatedKey with ErrInvalidCursor.
+func (r *DynamoDbClient) ParallelScan(indexName *string, arrayOfField string, queryOption QueryOption, totalSegments int, workers int, handler func(segment int, items []map[string]types.AttributeValue) error, options ...ReadOption) (err error) {
+	return r.ParallelScanCtx(context.TODO(), indexName, arrayOfField, queryOption, totalSegments, workers, handler, options...)
+}
+
+func (r *DynamoDbClient) ParallelScanCtx(ctx context.Context, indexName *string, arrayOfField string, queryOption QueryOption, totalSegments int, workers int, handler func(segment int, items []map[string]types.AttributeValue) error, options ...ReadOption) (err error) {
+	var wg sync.WaitGroup
+	var once sync.Once
+	var o = newReadOption(options)
+
+	if totalSegments < 1 {
+		totalSegments = 1
+	}
+
+	if workers < 1 || workers > totalSegments {
+		workers = totalSegments
+	}
+
+	if len(queryOption.Order) > 0 {
+		err = &InvalidOrderError{Field: queryOption.Order[0].Field, Reason: "a parallel scan cannot be ordered"}
+		return
+	}
+
+	if nil != queryOption.Page {
+		var exclusiveStartKey map[string]types.AttributeValue
+
+		exclusiveStartKey, err = r.decodeLastEvaluatedKey(scanCursorBinding(indexName), nil, queryOption.Page.LastEvaluatedKey)
+		if err != nil {
+			return
+		}
+
+		if nil != exclusiveStartKey {
+			err = &InvalidCursorError{Reason: "a parallel scan cannot start from a cursor"}
+			return
+		}
+	}
+
+	// validate the filter once rather than in every segment
+	if _, err = r.buildScanInput(indexName, arrayOfField, queryOption, o); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	segments := make(chan int)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for segment := range segments {
+				if segmentErr := r.scanSegment(ctx, indexName, arrayOfField, queryOption, o, segment, totalSegments, handler); segmentErr != nil {
+					once.Do(func() {
+						err = segmentErr
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	for segment := 0; segment < totalSegments && nil == ctx.Err(); segment++ {
+		segments <- segment
+	}
+
+	close(segments)
+	wg.Wait()
+
+	if nil == err {
+		err = ctx.Err()
+	}
+
+	return
+}
+
+func (r *DynamoDbClient) scanSegment(ctx context.Context, indexName *string, arrayOfField string, queryOption QueryOption, o readOption, segment int, totalSegments int, handler func(segment int, items []map[string]types.AttributeValue) error) (err error) {
+	input, err := r.buildScanInput(indexName, arrayOfField, queryOption, o)
+	if err != nil {
+		return
+	}
+
+	input.Segment = aws.Int32(int32(segment))
+	input.TotalSegments = aws.Int32(int32(totalSegments))
+
+	if nil != queryOption.Page && queryOption.Page.PageSize > 0 {
+		input.Limit = aws.Int32(int32(queryOption.Page.PageSize))
+	}
+
+	paginator := dynamodb.NewScanPaginator(r.dynamoDb, input)
+
+	for paginator.HasMorePages() {
+		var output *dynamodb.ScanOutput
+
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		output, err = paginator.NextPage(ctx)
+		if err != nil {
+			return
+		}
+
+		if 0 == len(output.Items) {
+			continue
+		}
+
+		if err = handler(segment, output.Items); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (r *DynamoDbClient) buildScanInput(indexName *string, arrayOfField string, queryOption QueryOption, o readOption) (input *dynamodb.ScanInput, err error) {
+	var expressionAttributeNames = make(map[string]string)
+	var expressionAttributeValues = make(map[string]types.AttributeValue)
+	var projection = o.projectionOf(arrayOfField)
+
+	if o.consistentRead && nil != indexName {
+		err = &ConsistentReadOnIndexError{Key: Key{IndexName: indexName}}
+		return
+	}
+
+	input = &dynamodb.ScanInput{
+		TableName:      aws.String(r.tableName),
+		IndexName:      indexName,
+		ConsistentRead: o.consistentReadInput(),
+	}
+
+	if nil != queryOption.Filter || nil != queryOption.Condition {
+		var filterExpression string
+
+		filterExpression, err = processQueryOptionCondition(queryOption, expressionAttributeValues, expressionAttributeNames)
+		if err != nil {
+			return
+		}
+
+		if "" != filterExpression {
+			input.FilterExpression = aws.String(filterExpression)
+		}
+	}
+
+	if len(projection) > 0 {
+		var projectionExpression string
+
+		projectionExpression, err = buildProjectionExpression(projection, expressionAttributeNames)
+		if err != nil {
+			return
+		}
+
+		input.ProjectionExpression = aws.String(projectionExpression)
+	}
+
+	if len(expressionAttributeNames) > 0 {
+		input.ExpressionAttributeNames = expressionAttributeNames
+	}
+
+	if len(expressionAttributeValues) > 0 {
+		input.ExpressionAttributeValues = expressionAttributeValues
+	}
+
+	return
+}
+
+// scanCursorBinding binds the cursors of a scan to its index.
+func scanCursorBinding(indexName *string) string {
+	return cursorBinding("scan", aws.ToString(indexName))
+}
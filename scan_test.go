@@ -0,0 +1,171 @@
+package dynamodb_client_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	dc "gitlab.com/ptami_lib/dynamodb-client"
+	"gitlab.com/ptami_lib/dynamodb-client/dynamodbtest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScanPage(t *testing.T) {
+	client, _ := newTestClient(t)
+	insertTestRecords(t, client, 5)
+
+	items, cursor, err := client.Scan(nil, "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: 2}})
+	if err != nil || 2 != len(items) || "" == cursor {
+		t.Fatalf("Scan() = %d item(s), %q, %v, want a page of 2 and a cursor", len(items), cursor, err)
+	}
+
+	items, _, err = client.Scan(nil, "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: 2, LastEvaluatedKey: cursor}})
+	if err != nil || 2 != len(items) {
+		t.Errorf("Scan() of the next page = %d item(s), %v, want 2", len(items), err)
+	}
+
+	items, cursor, err = client.Scan(nil, "", dc.QueryOption{Page: &dc.QueryOptionPage{AllInOne: true, PageSize: 2}})
+	if err != nil || 5 != len(items) || "" != cursor {
+		t.Errorf("Scan(AllInOne) = %d item(s), %q, %v, want 5 and no cursor", len(items), cursor, err)
+	}
+
+	table := &recordingTable{Table: dynamodbtest.New("t"), pageSize: 2}
+	paged := dc.New(table, "t")
+	insertTestRecords(t, paged, 5)
+
+	items, cursor, err = paged.Scan(nil, "", dc.QueryOption{})
+	if err != nil || 2 != len(items) || "" == cursor || 1 != table.scans {
+		t.Errorf("Scan() without Page = %d item(s), %q, %v in %d scan(s), want a single page", len(items), cursor, err, table.scans)
+	}
+
+	_, _, err = client.Scan(nil, "", dc.QueryOption{Order: []dc.QueryOptionOrder{{Field: "Count"}}})
+	if !errors.Is(err, dc.ErrInvalidOrder) {
+		t.Errorf("Scan() ordered without AllInOne error = %v, want ErrInvalidOrder", err)
+	}
+}
+
+// insertPartitions inserts one record in each of the partitions P00 to
+// P<count-1>, spreading them over the segments of a parallel scan.
+func insertPartitions(t *testing.T, client *dc.DynamoDbClient, count int) {
+	t.Helper()
+
+	for i := 0; i < count; i++ {
+		record := testRecord{Count: i}
+		record.PK, record.SK = fmt.Sprintf("P%02d", i), "S#00"
+
+		if err := client.Insert(record); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+}
+
+func TestParallelScan(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	var got []int
+
+	client, _ := newTestClient(t)
+	insertPartitions(t, client, 20)
+
+	segments := map[int]bool{}
+
+	err := client.ParallelScan(nil, "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: 2}}, 4, 2, func(segment int, items []map[string]types.AttributeValue) error {
+		var records []testRecord
+
+		if err := attributevalue.UnmarshalListOfMaps(items, &records); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+
+		segments[segment] = true
+		got = append(got, counts(records)...)
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ParallelScan() error = %v", err)
+	}
+
+	sort.Ints(got)
+
+	if want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParallelScan() read %v, want every item once", got)
+	}
+
+	if 4 != len(segments) {
+		t.Errorf("ParallelScan() read segments %v, want 0 to 3", segments)
+	}
+
+	if maxRunning > 2 {
+		t.Errorf("ParallelScan() ran %d handlers at once, want at most 2 workers", maxRunning)
+	}
+}
+
+func TestParallelScanHandlerError(t *testing.T) {
+	var calls []int
+
+	client, _ := newTestClient(t)
+	insertPartitions(t, client, 20)
+
+	stop := errors.New("stop")
+
+	err := client.ParallelScan(nil, "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: 2}}, 4, 1, func(segment int, items []map[string]types.AttributeValue) error {
+		calls = append(calls, segment)
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("ParallelScan() error = %v, want the handler error", err)
+	}
+
+	if 1 != len(calls) {
+		t.Errorf("ParallelScan() called the handler for segments %v after its error, want a single call", calls)
+	}
+
+	err = client.ParallelScan(nil, "", dc.QueryOption{Filter: map[string]interface{}{
+		"status": map[string]interface{}{"field": "Status", "type": "unknown", "keyword": "A"},
+	}}, 4, 2, func(int, []map[string]types.AttributeValue) error { return nil })
+	if !errors.Is(err, dc.ErrInvalidFilter) {
+		t.Errorf("ParallelScan() error = %v, want ErrInvalidFilter", err)
+	}
+}
+
+func TestParallelScanRejected(t *testing.T) {
+	client, _ := newTestClient(t)
+	insertPartitions(t, client, 4)
+
+	handler := func(int, []map[string]types.AttributeValue) error {
+		t.Errorf("ParallelScan() called the handler of a rejected scan")
+		return nil
+	}
+
+	_, cursor, err := client.Scan(nil, "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: 2}})
+	if err != nil || "" == cursor {
+		t.Fatalf("Scan() = %q, %v", cursor, err)
+	}
+
+	err = client.ParallelScan(nil, "", dc.QueryOption{Page: &dc.QueryOptionPage{PageSize: 2, LastEvaluatedKey: cursor}}, 2, 2, handler)
+	if !errors.Is(err, dc.ErrInvalidCursor) {
+		t.Errorf("ParallelScan() from a cursor error = %v, want ErrInvalidCursor", err)
+	}
+
+	err = client.ParallelScan(nil, "", dc.QueryOption{Order: []dc.QueryOptionOrder{{Field: "SK"}}}, 2, 2, handler)
+	if !errors.Is(err, dc.ErrInvalidOrder) {
+		t.Errorf("ParallelScan() with an order error = %v, want ErrInvalidOrder", err)
+	}
+}
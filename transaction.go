@@ -0,0 +1,336 @@
+package dynamodb_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const maxTransactionItems = 100
+
+// TransactionCancellationReason is the outcome of the operation at Index of a
+// canceled transaction; Code is "None" for operations that did not cause the
+// cancellation. Versioned reports an operation whose only condition is its
+// Version, so that a ConditionalCheckFailed code is a version conflict.
+type TransactionCancellationReason struct {
+	Index     int
+	Key       Key
+	Code      string
+	Message   string
+	Versioned bool
+}
+
+type TransactionCanceledError struct {
+	Reasons []TransactionCancellationReason
+	Err     error
+}
+
+func (e *TransactionCanceledError) Error() string {
+	for _, reason := range e.Reasons {
+		if "None" != reason.Code {
+			return fmt.Sprintf("transaction canceled: operation %d failed with %s", reason.Index, reason.Code)
+		}
+	}
+
+	return "transaction canceled"
+}
+
+// Is matches ErrTransactionCanceled, ErrConditionFailed when an operation
+// failed its condition, and ErrVersionConflict when a versioned operation
+// did.
+func (e *TransactionCanceledError) Is(target error) bool {
+	if ErrConditionFailed == target || ErrVersionConflict == target {
+		for _, reason := range e.Reasons {
+			if "ConditionalCheckFailed" == reason.Code && (ErrConditionFailed == target || reason.Versioned) {
+				return true
+			}
+		}
+	}
+
+	return ErrTransactionCanceled == target
+}
+
+func (e *TransactionCanceledError) Unwrap() error {
+	return e.Err
+}
+
+// Transaction queues write operations that are committed atomically with
+// TransactWriteItems. The first error met while queueing is returned by
+// Commit.
+type Transaction struct {
+	client *DynamoDbClient
+	items  []types.TransactWriteItem
+	keys   []Key
+	err    error
+
+	// versioned tells which operations only check their Version.
+	versioned []bool
+
+	// committed runs once the transaction succeeded, e.g. to store the new
+	// Version of the items put.
+	committed []func()
+}
+
+func (r *DynamoDbClient) NewTransaction() *Transaction {
+	return &Transaction{client: r}
+}
+
+func (t *Transaction) Put(item interface{}, options ...WriteOption) *Transaction {
+	if nil != t.err {
+		return t
+	}
+
+	o := newWriteOption(options)
+
+	avItem, metaData, err := t.client.marshalItem(item)
+	if err != nil {
+		t.err = err
+		return t
+	}
+
+	o.versionFromMetaData(metaData)
+	o.applyVersion(avItem)
+
+	put := &types.Put{
+		Item:      avItem,
+		TableName: aws.String(t.client.tableName),
+	}
+
+	put.ConditionExpression, put.ExpressionAttributeNames, put.ExpressionAttributeValues, err = o.buildCondition(true, nil, nil)
+	if err != nil {
+		t.err = err
+		return t
+	}
+
+	t.committed = append(t.committed, func() { o.storeVersion(metaData) })
+
+	return t.add(keyOfItem(avItem), types.TransactWriteItem{Put: put}, o.versionOnly())
+}
+
+// Update queues an update of key with the same propertyMap semantics as
+// UpdateItem.
+func (t *Transaction) Update(key Key, propertyMap map[string]interface{}, options ...WriteOption) *Transaction {
+	if nil != t.err {
+		return t
+	}
+
+	o := newWriteOption(options)
+
+	keyAv, err := marshalPrimaryKey(key)
+	if err != nil {
+		t.err = err
+		return t
+	}
+
+	updateExpression, expressionAttributeNames, expressionAv, err := t.client.buildUpdateExpression(propertyMap, o)
+	if err != nil {
+		t.err = err
+		return t
+	}
+
+	update := &types.Update{
+		Key:              keyAv,
+		TableName:        aws.String(t.client.tableName),
+		UpdateExpression: aws.String(updateExpression),
+	}
+
+	update.ConditionExpression, update.ExpressionAttributeNames, update.ExpressionAttributeValues, err = o.buildCondition(false, expressionAttributeNames, expressionAv)
+	if err != nil {
+		t.err = err
+		return t
+	}
+
+	return t.add(key, types.TransactWriteItem{Update: update}, o.versionOnly())
+}
+
+func (t *Transaction) Delete(key Key, options ...WriteOption) *Transaction {
+	if nil != t.err {
+		return t
+	}
+
+	o := newWriteOption(options)
+
+	keyAv, err := marshalPrimaryKey(key)
+	if err != nil {
+		t.err = err
+		return t
+	}
+
+	del := &types.Delete{
+		Key:       keyAv,
+		TableName: aws.String(t.client.tableName),
+	}
+
+	del.ConditionExpression, del.ExpressionAttributeNames, del.ExpressionAttributeValues, err = o.buildCondition(false, nil, nil)
+	if err != nil {
+		t.err = err
+		return t
+	}
+
+	return t.add(key, types.TransactWriteItem{Delete: del}, o.versionOnly())
+}
+
+// ConditionCheck queues a check that the item at key matches condition,
+// given in the same shape as QueryOption.Filter.
+func (t *Transaction) ConditionCheck(key Key, condition map[string]interface{}) *Transaction {
+	if nil != t.err {
+		return t
+	}
+
+	c, err := ConditionFromFilter(condition)
+	if err != nil {
+		t.err = err
+		return t
+	}
+
+	return t.CheckCondition(key, c)
+}
+
+// CheckCondition queues a check that the item at key matches condition.
+func (t *Transaction) CheckCondition(key Key, condition Condition) *Transaction {
+	var expressionAttributeValues = map[string]types.AttributeValue{}
+	var expressionAttributeNames = map[string]string{}
+
+	if nil != t.err {
+		return t
+	}
+
+	keyAv, err := marshalPrimaryKey(key)
+	if err != nil {
+		t.err = err
+		return t
+	}
+
+	conditionExpression, err := buildConditionExpression(condition, expressionAttributeValues, expressionAttributeNames)
+	if err != nil {
+		t.err = err
+		return t
+	}
+
+	if "" == conditionExpression {
+		t.err = &InvalidFilterError{Reason: "condition check requires a condition"}
+		return t
+	}
+
+	return t.add(key, types.TransactWriteItem{ConditionCheck: &types.ConditionCheck{
+		Key:                       keyAv,
+		TableName:                 aws.String(t.client.tableName),
+		ConditionExpression:       aws.String(conditionExpression),
+		ExpressionAttributeNames:  expressionAttributeNames,
+		ExpressionAttributeValues: expressionAttributeValues,
+	}}, false)
+}
+
+func (t *Transaction) add(key Key, item types.TransactWriteItem, versioned bool) *Transaction {
+	t.items = append(t.items, item)
+	t.keys = append(t.keys, key)
+	t.versioned = append(t.versioned, versioned)
+
+	return t
+}
+
+func (t *Transaction) Commit() (err error) {
+	return t.CommitCtx(context.TODO())
+}
+
+// CommitCtx runs the queued operations; when DynamoDB cancels the
+// transaction, a *TransactionCanceledError carries the reason of every
+// operation.
+func (t *Transaction) CommitCtx(ctx context.Context) (err error) {
+	if nil != t.err {
+		return t.err
+	}
+
+	if 0 == len(t.items) || len(t.items) > maxTransactionItems {
+		err = &InvalidTransactionSizeError{Operations: len(t.items)}
+		return
+	}
+
+	_, err = t.client.dynamoDb.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: t.items,
+	})
+
+	var transactionCanceled *types.TransactionCanceledException
+	if errors.As(err, &transactionCanceled) {
+		reasons := make([]TransactionCancellationReason, len(transactionCanceled.CancellationReasons))
+
+		for i, reason := range transactionCanceled.CancellationReasons {
+			reasons[i] = TransactionCancellationReason{
+				Index:   i,
+				Code:    aws.ToString(reason.Code),
+				Message: aws.ToString(reason.Message),
+			}
+
+			if i < len(t.keys) {
+				reasons[i].Key = t.keys[i]
+				reasons[i].Versioned = t.versioned[i]
+			}
+		}
+
+		err = &TransactionCanceledError{Reasons: reasons, Err: err}
+	}
+
+	if nil == err {
+		for _, committed := range t.committed {
+			committed()
+		}
+	}
+
+	return
+}
+
+func (r *DynamoDbClient) TransactGet(keys []Key) (items []map[string]types.AttributeValue, err error) {
+	return r.TransactGetCtx(context.TODO(), keys)
+}
+
+// TransactGetCtx reads keys as a single snapshot with TransactGetItems and
+// returns their items in the order of keys. Like GetItem, a missing item
+// fails the call with a *NotFoundError for the first key that was not found.
+func (r *DynamoDbClient) TransactGetCtx(ctx context.Context, keys []Key) (items []map[string]types.AttributeValue, err error) {
+	var output *dynamodb.TransactGetItemsOutput
+
+	if 0 == len(keys) || len(keys) > maxTransactionItems {
+		err = &InvalidTransactionSizeError{Operations: len(keys)}
+		return
+	}
+
+	transactItems := make([]types.TransactGetItem, len(keys))
+
+	for i, key := range keys {
+		var keyAv map[string]types.AttributeValue
+
+		keyAv, err = marshalPrimaryKey(key)
+		if err != nil {
+			return
+		}
+
+		transactItems[i] = types.TransactGetItem{Get: &types.Get{
+			Key:       keyAv,
+			TableName: aws.String(r.tableName),
+		}}
+	}
+
+	output, err = r.dynamoDb.TransactGetItems(ctx, &dynamodb.TransactGetItemsInput{
+		TransactItems: transactItems,
+	})
+	if err != nil {
+		return
+	}
+
+	items = make([]map[string]types.AttributeValue, len(keys))
+
+	for i, response := range output.Responses {
+		if nil == response.Item {
+			items = nil
+			err = &NotFoundError{Key: keys[i]}
+			return
+		}
+
+		items[i] = response.Item
+	}
+
+	return
+}